@@ -12,6 +12,10 @@ require (
 	github.com/nickwells/testhelper.mod/v2 v2.6.1
 	github.com/nickwells/verbose.mod v1.1.24
 	github.com/nickwells/versionparams.mod v1.2.28
+	github.com/nickwells/xdg.mod v1.0.12
+	golang.org/x/mod v0.41.0
+	golang.org/x/term v0.43.0
+	golang.org/x/tools v0.51.0
 )
 
 require (
@@ -27,8 +31,7 @@ require (
 	github.com/nickwells/pager.mod v1.1.0 // indirect
 	github.com/nickwells/tempus.mod v1.2.11 // indirect
 	github.com/nickwells/twrap.mod v1.5.14 // indirect
-	github.com/nickwells/xdg.mod v1.0.12 // indirect
 	golang.org/x/exp v0.0.0-20260508232706-74f9aab9d74a // indirect
-	golang.org/x/sys v0.45.0 // indirect
-	golang.org/x/term v0.43.0 // indirect
+	golang.org/x/sync v0.23.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
 )
@@ -13,10 +13,27 @@ const (
 	noteNameTemplateDir    = noteBaseName + "Template directories"
 	noteNameGeneratedFiles = noteBaseName + "Template files - generated"
 	noteNameCheckFiles     = noteBaseName + "Template files - checks"
+	noteNameRequiresFiles  = noteBaseName +
+		"Template files - module requirements"
+	noteNamePermsFiles  = noteBaseName + "Template files - permissions"
+	noteNameSymlinks    = noteBaseName + "Template files - symbolic links"
+	noteNameHooks       = noteBaseName + "Template files - hooks"
+	noteNameExitStatus  = noteBaseName + "Exit status"
+	noteNameConfigFiles = noteBaseName + "Configuration files"
 )
 
 // addNotes adds the notes, if any, for this program
 func addNotes(prog *Prog) param.PSetOptFunc {
+	var severityNotes strings.Builder
+
+	for _, sd := range severityDescs {
+		severityNotes.WriteString("- ")
+		severityNotes.WriteString(string(sd.sev))
+		severityNotes.WriteString(" : ")
+		severityNotes.WriteString(sd.desc)
+		severityNotes.WriteString("\n")
+	}
+
 	var checkTypeNotes strings.Builder
 
 	var ctiContains checkTypeInfo
@@ -52,6 +69,12 @@ func addNotes(prog *Prog) param.PSetOptFunc {
 		noteNameTemplateDir,
 		noteNameGeneratedFiles,
 		noteNameCheckFiles,
+		noteNameRequiresFiles,
+		noteNamePermsFiles,
+		noteNameSymlinks,
+		noteNameHooks,
+		noteNameExitStatus,
+		noteNameConfigFiles,
 	}
 
 	startMacro, endMacro := prog.macroCache.GetStartEndStrings()
@@ -70,9 +93,23 @@ func addNotes(prog *Prog) param.PSetOptFunc {
 				"- create checks to be performed on the content of the"+
 				" files in the resulting directory.\n"+
 				"- generate files that are not a straightforward copy but"+
-				" instead have various values substituted at run time.",
+				" instead have various values substituted at run time."+
+				"\n\n"+
+				"You can check a template directory for problems by"+
+				" giving the '"+paramNameAction+"' parameter a value"+
+				" of '"+string(aLintTemplate)+"'. This reports all the"+
+				" problems it finds rather than stopping at the first."+
+				" You can check that a program directory created from"+
+				" the template passes the template's own checks by"+
+				" giving it a value of '"+string(aTestTemplate)+"'."+
+				" You can make a new template directory from an existing"+
+				" program directory by giving it a value"+
+				" of '"+string(aMakeTemplate)+"'. You can see what a"+
+				" template directory will produce by giving it a value"+
+				" of '"+string(aDescTemplate)+"'.",
 			param.NoteSeeNote(noteNames...),
-			param.NoteSeeParam(paramNameTemplateDir),
+			param.NoteSeeParam(paramNameTemplateDir, paramNameAction,
+				paramNameNewTemplateDir),
 		)
 		ps.AddNote(noteNameGeneratedFiles,
 			"To generate a file that is not just a copy of the template"+
@@ -87,9 +124,15 @@ func addNotes(prog *Prog) param.PSetOptFunc {
 				genMacroNotes.String()+
 				"\n"+
 				"The macro name must be surrounded"+
-				" by '"+startMacro+"' and '"+endMacro+"'.",
+				" by '"+startMacro+"' and '"+endMacro+"'."+
+				"\n\n"+
+				"Any other macros used by the template must be given"+
+				" values, either with the '"+paramNameMacro+"'"+
+				" parameter or, when creating interactively, in"+
+				" response to the prompts.",
 			param.NoteSeeNote(noteNames...),
-			param.NoteSeeParam(paramNameTemplateDir),
+			param.NoteSeeParam(paramNameTemplateDir,
+				paramNameMacro, paramNameInteractive),
 		)
 		ps.AddNote(noteNameCheckFiles,
 			"To generate checks of the contents of a file, add another"+
@@ -137,12 +180,261 @@ func addNotes(prog *Prog) param.PSetOptFunc {
 				" used to generate the check function."+
 				"\n"+
 				"The target file will again be 'xxx'. Again, no file will"+
-				" be generated for this entry.",
+				" be generated for this entry."+
+				"\n\n"+
+				"Each check has a severity which can be given by adding"+
+				" '"+sfxSeverity+"' followed by the severity immediately"+
+				" before the check suffix. The severity is shown when a"+
+				" check fails. The following severities are allowed:"+
+				"\n"+
+				severityNotes.String()+
+				"\n"+
+				"For example, having a file in the template directory"+
+				" called:"+
+				"\n"+
+				"   xxx"+ctiContains.suffix+sfxSeverity+string(sevWarning)+
+				sfxCheck+
+				"\n"+
+				"will generate a check which only gives a warning if it"+
+				" fails."+
+				"\n\n"+
+				"A target file can suppress some of the checks of its"+
+				" contents, if it legitimately differs from the template,"+
+				" by including a directive (typically in a comment) such"+
+				" as:"+
+				"\n"+
+				"   // "+suppressDirective+" begins,contains"+
+				` reason="a custom header"`+
+				"\n"+
+				"The check types are given without the leading '.'."+
+				" Suppressed checks that fail are reported separately and"+
+				" do not cause the check to fail. A directive that gives"+
+				" no reason or that no longer suppresses a failing check"+
+				" is reported as a problem."+
+				"\n\n"+
+				"Every check of a file is applied, so that all the"+
+				" failures are reported together, and once all the files"+
+				" have been checked a summary is given of the numbers of"+
+				" files, directories and symbolic links checked,"+
+				" passed, fixed, failed and missing, the number of"+
+				" checks run and the number of checks of the program"+
+				" directory as a whole that failed.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameTemplateDir,
+				paramNameCheck,
+				paramNameAction,
+				paramNameFailFast,
+				paramNameQuiet),
+		)
+		ps.AddNote(noteNameRequiresFiles,
+			"To require that the Go module containing the target"+
+				" directory uses sufficiently recent versions of the"+
+				" modules imported by the generated files, add a file to"+
+				" the template directory with the suffix"+
+				" '"+sfxRequires+"'. No file will be generated for this"+
+				" entry. Each line of the file should give a module path"+
+				" followed by the minimum version of that module;"+
+				" blank lines are ignored as is anything after a '#'."+
+				"\n\n"+
+				"When checking the target directory the "+goModFileName+
+				" file for the enclosing module is found and, for each"+
+				" module imported by the Go files in the target directory,"+
+				" the required version is compared with the minimum"+
+				" version. Any module which is not required or which is"+
+				" required at an earlier version is reported. When fixing"+
+				" the target directory the "+goModFileName+" file is"+
+				" updated to require the minimum version."+
+				"\n\n"+
+				"When creating the target directory, if it is not in a"+
+				" Go module and Go files have been generated, a"+
+				" "+goModFileName+" file is generated in the target"+
+				" directory. This will require each module imported by"+
+				" the generated files at the minimum version. The same"+
+				" is done when fixing a target directory which is not in"+
+				" a Go module.",
 			param.NoteSeeNote(noteNames...),
 			param.NoteSeeParam(
 				paramNameTemplateDir,
+				paramNameModulePath,
 				paramNameCheck,
-				paramNameAction),
+				paramNameFix),
+		)
+		ps.AddNote(noteNamePermsFiles,
+			"By default, files are created with the permissions given"+
+				" by the '"+paramNamePerms+"' parameter. If the template"+
+				" directory is on disk and that parameter is not given"+
+				" then each file and directory is instead created with"+
+				" the permissions of the corresponding entry in the"+
+				" template directory."+
+				"\n\n"+
+				"The permissions of an individual file can be set by"+
+				" adding the suffix '"+sfxPerms+"' followed by the"+
+				" permissions, as three octal digits optionally preceded"+
+				" by a '0', to the name of the template file. The"+
+				" setuid, setgid and sticky bits cannot be given and"+
+				" any other value is reported as a problem with the"+
+				" template. This"+
+				" is needed for the built-in templates which do not"+
+				" record the permissions of their files. The suffix"+
+				" should come before any '"+sfxGenerate+"' suffix. The"+
+				" file will always be created with these permissions,"+
+				" regardless of the"+
+				" value of the '"+paramNamePerms+"' parameter."+
+				"\n\n"+
+				"For example, having a file in the template directory"+
+				" called:"+
+				"\n"+
+				"   build.sh"+sfxPerms+"0755"+
+				"\n"+
+				"will create a file called 'build.sh' which is executable."+
+				"\n\n"+
+				"When the permissions are checked each file is checked"+
+				" against its own expected permissions.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameTemplateDir,
+				paramNamePerms,
+				paramNameCheckPerms),
+		)
+		ps.AddNote(noteNameSymlinks,
+			"To create a symbolic link in the target directory, add a"+
+				" file to the template directory with the suffix"+
+				" '"+sfxSymlink+"'. The contents of the file, with any"+
+				" leading or trailing white space removed, give the"+
+				" target of the link. If the link target relies on values"+
+				" that need to have macro substitution performed on them"+
+				" then the filename should have the symbolic link suffix"+
+				" followed by the generate suffix."+
+				"\n\n"+
+				"For example, having a file in the template directory"+
+				" called:"+
+				"\n"+
+				"   LICENSE"+sfxSymlink+
+				"\n"+
+				"containing '../LICENSE' will create a symbolic link"+
+				" called 'LICENSE' pointing to '../LICENSE'."+
+				"\n\n"+
+				"If the template directory is on disk then any symbolic"+
+				" links it contains are created as symbolic links with"+
+				" the same link target."+
+				"\n\n"+
+				"When checking the target directory, each link must"+
+				" exist, be a symbolic link, point to the expected link"+
+				" target and that link target must exist. When fixing"+
+				" the target directory any missing link is created and"+
+				" any link pointing to the wrong place, or any file in"+
+				" the place of a link, is replaced.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameTemplateDir,
+				paramNameCheck,
+				paramNameFix),
+		)
+		ps.AddNote(noteNameHooks,
+			"To run commands after the files have been created or"+
+				" fixed, add a file to the template directory with the"+
+				" suffix '"+sfxHook+"'. The rest of the name gives the"+
+				" event after which the commands are run, either"+
+				" '"+string(hookPostCreate)+"' or"+
+				" '"+string(hookPostFix)+"', optionally followed by a"+
+				" numeric ID. Hook files are run in order of their"+
+				" ID, a hook file without an ID is run first."+
+				" No file will be generated for this entry."+
+				"\n\n"+
+				"Each line of the file gives a command to run followed by"+
+				" its arguments, separated by white space; blank lines"+
+				" are ignored as is anything from a '#' at the start of"+
+				" a word to the end of the line. As in a shell, an"+
+				" argument containing white space can be given in"+
+				" quotes. Characters between single quotes are taken"+
+				" literally. Between double quotes a '\\' before a"+
+				" '\"' or a '\\' stands for that character. Elsewhere a"+
+				" '\\' stands for the following character. This allows a"+
+				" macro whose value contains white space to be given as"+
+				" a single argument, for instance:"+
+				"\n"+
+				"   git commit -m "+`"`+startMacro+"Description"+endMacro+`"`+
+				"\n"+
+				"The commands are"+
+				" run in the target directory and their output is"+
+				" reported. If a command fails then no further commands"+
+				" are run and the program will exit with a non-zero"+
+				" status. If the commands rely on values that need to"+
+				" have macro substitution performed on them then the"+
+				" filename should have the hook suffix followed by the"+
+				" generate suffix."+
+				"\n\n"+
+				"For example, having a file in the template directory"+
+				" called:"+
+				"\n"+
+				"   "+string(hookPostCreate)+".1"+sfxHook+
+				"\n"+
+				"containing:"+
+				"\n"+
+				"   gofmt -l ."+
+				"\n"+
+				"   go generate"+
+				"\n"+
+				"will run those two commands after the target directory"+
+				" has been successfully created.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameTemplateDir,
+				paramNameNoHooks,
+				paramNameFix),
+		)
+		ps.AddNote(noteNameExitStatus,
+			"The program exits with a status showing the category of"+
+				" problem that was found. If problems of more than one"+
+				" category are found the exit status is the highest of"+
+				" them. The exit statuses are:"+
+				"\n"+
+				exitStatusNotes()+
+				"\n"+
+				"Note that problems with the parameters are reported"+
+				" before anything else is done and the program then"+
+				" exits with a non-zero status.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameAction,
+				paramNameWarningsAsErrors),
+		)
+		ps.AddNote(noteNameConfigFiles,
+			"Default values for some of the parameters, such as the"+
+				" template directory, the permissions, the macros and"+
+				" the report format, can be given in configuration"+
+				" files rather than on every command line. Parameters"+
+				" which can only be given on the command line are"+
+				" reported as errors if they appear in these files."+
+				" Each line of the file should give the parameter name"+
+				" followed by '=' and the value; blank lines are"+
+				" ignored as is anything after a '#'."+
+				"\n\n"+
+				"The per-user configuration file is:"+
+				"\n"+
+				"   "+userConfigFile()+
+				"\n"+
+				"It is read first. Then, if a file called"+
+				" '"+projectConfigFileName+"' is found in the target"+
+				" directory or in any directory above it, the nearest"+
+				" such file is read as the per-project configuration"+
+				" file. Values given in the per-project file take"+
+				" precedence over those in the per-user file and values"+
+				" given on the command line take precedence over both."+
+				" Note that a relative pathname given in either file"+
+				" is taken relative to the directory holding that"+
+				" file rather than the current directory."+
+				"\n\n"+
+				"The help parameters will show where each parameter"+
+				" value has been set.",
+			param.NoteSeeNote(noteNames...),
+			param.NoteSeeParam(
+				paramNameTemplateDir,
+				paramNamePerms,
+				paramNameMacro,
+				paramNameReportFormat,
+				paramNameProgName),
 		)
 
 		return nil
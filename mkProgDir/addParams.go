@@ -14,6 +14,7 @@ import (
 	"github.com/nickwells/param.mod/v7/paction"
 	"github.com/nickwells/param.mod/v7/param"
 	"github.com/nickwells/param.mod/v7/psetter"
+	"golang.org/x/mod/module"
 )
 
 const (
@@ -23,12 +24,62 @@ const (
 	paramNameFix                   = "fix"
 	paramNamePerms                 = "permissions"
 	paramNameCheckPerms            = "check-permissions"
+	paramNamePermsCheckMode        = "check-permissions-mode"
 	paramNameTemplateDir           = "template-directory"
 	paramNameReportMissingOptFiles = "report-missing-optional-files"
+	paramNameCheckBuild            = "check-build"
+	paramNameNoHooks               = "no-hooks"
+	paramNameModulePath            = "module-path"
+	paramNameAddToWorkspace        = "add-to-workspace"
+	paramNameRecursive             = "recursive"
+	paramNameJobs                  = "jobs"
+	paramNameNewTemplateDir        = "new-template-directory"
+	paramNameAddBeginsChecks       = "add-begins-checks"
+	paramNameReportFormat          = "report-format"
+	paramNameMacro                 = "macro"
+	paramNameInteractive           = "interactive"
+	paramNameOnConflict            = "on-conflict"
+	paramNameStrict                = "strict"
+	paramNameWarningsAsErrors      = "warnings-as-errors"
+	paramNameFailFast              = "fail-fast"
+	paramNameQuiet                 = "quiet"
 )
 
 var progNameRE = regexp.MustCompile("[a-zA-Z][-_.a-zA-Z0-9]*")
 
+// checkProgName checks that the program name is valid
+var checkProgName = check.StringMatchesPattern[string](
+	progNameRE,
+	"a string starting with a letter and"+
+		" followed by zero or more"+
+		" letters, digits,"+
+		" '.', '-' or '_'")
+
+// checkNotInDir returns an error if the path is the directory or is
+// inside it
+func checkNotInDir(path, dir string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil { // the path can't be relative to the dir so isn't in it
+		return nil //nolint:nilerr
+	}
+
+	if rel == "." || filepath.IsLocal(rel) {
+		return fmt.Errorf("%q is inside the directory %q", path, dir)
+	}
+
+	return nil
+}
+
 // addParams adds the parameters for this program
 func addParams(prog *Prog) param.PSetOptFunc {
 	return func(ps *param.PSet) error {
@@ -42,6 +93,39 @@ func addParams(prog *Prog) param.PSetOptFunc {
 						" that the standard files are all present.",
 					aFix: "fix the target directory (which should exist) and" +
 						" copy in any missing files.",
+					aLintTemplate: "check the template directory for" +
+						" problems, such as badly formed regular" +
+						" expressions, undefined macros or checks of" +
+						" files that are not in the template, and report" +
+						" them all. The program name need not be given.",
+					aTestTemplate: "create a program directory from the" +
+						" template in a temporary directory and check it," +
+						" reporting any check that fails. If a program" +
+						" name is given, the last part of it is used as" +
+						" the name of the program, otherwise a sample" +
+						" name is used. Any macro used by the template" +
+						" which has no value is given a placeholder" +
+						" value. If the build is to be checked" +
+						" then the program is also built.",
+					aMakeTemplate: "make a new template directory from" +
+						" the program directory (which should exist)." +
+						" Occurrences of the program name in the files" +
+						" are replaced with the program name macro and" +
+						" those files become generated files. Files" +
+						" matched by any " + gitignoreFileName + " files" +
+						" are not copied.",
+					aDescTemplate: "describe the files in the template" +
+						" directory, what each will produce, the checks" +
+						" applied to each target and the macros used by" +
+						" each generated file. The program name need not" +
+						" be given.",
+					aUndo: "undo the changes made to the program" +
+						" directory (which should exist) by the most" +
+						" recent run that created or fixed it. Files" +
+						" that were made are removed and files that were" +
+						" changed or moved aside are restored. Nothing" +
+						" is undone if any of the files have been" +
+						" changed since.",
 				},
 			},
 			"The action to perform.",
@@ -79,8 +163,7 @@ func addParams(prog *Prog) param.PSetOptFunc {
 				" directory must not exist. If you are checking or"+
 				" fixing a directory then it must exist.",
 			param.Attrs(param.CommandLineOnly),
-			param.AltNames("prog-name", "name"),
-			param.Attrs(param.MustBeSet),
+			param.AltNames(progNameParamNames[1:]...),
 			param.PostAction(
 				func(_ location.L, _ *param.BaseParam, _ []string) error {
 					dir := filepath.Clean(prog.dir)
@@ -92,30 +175,234 @@ func addParams(prog *Prog) param.PSetOptFunc {
 							prog.dir)
 					}
 
-					return check.StringMatchesPattern[string](
-						progNameRE,
-						"a string starting with a letter and"+
-							" followed by zero or more"+
-							" letters, digits,"+
-							" '.', '-' or '_'")(prog.name)
+					return nil
 				}),
 		)
 
-		ps.Add(paramNameTemplateDir,
+		ps.AddFinalCheck(func() error {
+			if prog.dir != "" {
+				return nil
+			}
+
+			switch prog.action {
+			case aLintTemplate, aTestTemplate, aDescTemplate:
+				return nil
+			}
+
+			return fmt.Errorf(
+				"the %q parameter must be given when the action is %q",
+				paramNameProgName, prog.action)
+		})
+
+		newTemplateDirParam := ps.Add(paramNameNewTemplateDir,
 			psetter.Pathname{
-				Value: &prog.templateDirName,
+				Value: &prog.newTemplateDir,
 				Expectation: filecheck.Provisos{
-					Existence: filecheck.MustExist,
-					Checks:    []check.FileInfo{check.FileInfoIsDir},
+					Existence: filecheck.MustNotExist,
 				},
 			},
+			"The name of the template directory to be made from the"+
+				" program directory. It must not already exist and it"+
+				" must not be inside the program directory.",
+			param.AltNames("new-template-dir", "new-template"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameAction, paramNameAddBeginsChecks),
+		)
+
+		addBeginsChecksParam := ps.Add(paramNameAddBeginsChecks,
+			psetter.Int[int]{
+				Value: &prog.beginsCheckLines,
+				Checks: []check.ValCk[int]{
+					check.ValGT(0),
+				},
+			},
+			"When making a new template directory, add a check file"+
+				" for each Go file which will check that the file"+
+				" begins with the given number of lines from the"+
+				" original file.",
+			param.AltNames("begins-checks"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameNewTemplateDir),
+			param.SeeNote(noteNameCheckFiles),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.action == aMakeTemplate {
+				if prog.newTemplateDir == "" {
+					return fmt.Errorf(
+						"the %q parameter must be given when the"+
+							" action is %q",
+						paramNameNewTemplateDir, prog.action)
+				}
+
+				return checkNotInDir(prog.newTemplateDir, prog.dir)
+			}
+
+			for _, p := range []*param.ByName{
+				newTemplateDirParam,
+				addBeginsChecksParam,
+			} {
+				if p.HasBeenSet() {
+					return fmt.Errorf(
+						"the %q parameter has been given (at %s)"+
+							" but the action to be performed is not %q",
+						p.Name(),
+						english.Join(p.WhereSet(), ", ", " and "),
+						aMakeTemplate)
+				}
+			}
+
+			return nil
+		})
+
+		ps.Add(paramNameReportFormat,
+			psetter.Enum[reportFormat]{
+				Value: &prog.reportFormat,
+				AllowedVals: psetter.AllowedVals[reportFormat]{
+					rfTable: "a table with one row per template file",
+					rfJSON: "a JSON array with one object" +
+						" per template file",
+				},
+			},
+			"The format in which to show the description of the"+
+				" template. This is only used when the action is"+
+				" '"+string(aDescTemplate)+"'.",
+			param.AltNames("format"),
+			param.SeeAlso(paramNameAction),
+		)
+
+		ps.Add(paramNameMacro,
+			psetter.StrListAppender[string]{
+				Value: &prog.macroDefs,
+				Checks: []check.ValCk[string]{
+					checkMacroDef,
+				},
+			},
+			"Give a value to a macro used by the template. The value"+
+				" should be given as the macro name followed by"+
+				" '"+macroDefSep+"' and then the value. This can be"+
+				" given multiple times, once for each macro. Note that"+
+				" the macros that the program provides cannot be"+
+				" given a value.",
+			param.AltNames("m"),
+			param.SeeAlso(paramNameInteractive),
+			param.SeeNote(noteNameGeneratedFiles),
+		)
+
+		interactiveParam := ps.Add(paramNameInteractive,
+			psetter.Bool{
+				Value: &prog.interactive,
+			},
+			"When creating the program directory, prompt for a value"+
+				" for each macro used by the template, showing any"+
+				" value already given as the default. Then show the"+
+				" files that will be created and ask for confirmation"+
+				" before creating them."+
+				"\n\n"+
+				"The standard input must be a terminal. If it is not"+
+				" an error is reported listing any macros that have no"+
+				" value.",
+			param.AltNames("i"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameMacro),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if interactiveParam.HasBeenSet() &&
+				prog.action != aCreate {
+				return fmt.Errorf(
+					"you have asked for interactive creation"+
+						" (at %s) but the action to be performed"+
+						" is not to create the directory",
+					english.Join(interactiveParam.WhereSet(),
+						", ", " and "))
+			}
+
+			return nil
+		})
+
+		onConflictParam := ps.Add(paramNameOnConflict,
+			psetter.Enum[conflictPolicy]{
+				Value: &prog.onConflict,
+				AllowedVals: psetter.AllowedVals[conflictPolicy]{
+					ocFail: "report all the files that already exist" +
+						" and do not change anything.",
+					ocSkip: "leave the existing file unchanged.",
+					ocOverwrite: "replace the existing file with the" +
+						" file from the template.",
+					ocBackup: "move the existing file to a file with" +
+						" the same name but with '" + sfxBackup + "'" +
+						" added and then create the file from the" +
+						" template.",
+					ocWriteAlongside: "leave the existing file unchanged" +
+						" and create the file from the template with" +
+						" '" + sfxNew + "' added to its name. If" +
+						" that name is in use a number is added" +
+						" as well.",
+				},
+			},
+			"Allow the program directory to be created in a directory"+
+				" that already exists and say what should be done with"+
+				" any file that already exists and would be replaced by"+
+				" a file from the template. What was done to each such"+
+				" file is reported. Existing directories are left"+
+				" unchanged and the template files in them are added."+
+				"\n\n"+
+				"Note that if this is not given the program directory"+
+				" must not already exist.",
+			param.AltNames("if-exists"),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if onConflictParam.HasBeenSet() &&
+				prog.action != aCreate {
+				return fmt.Errorf(
+					"you have given a conflict policy (at %s)"+
+						" but the action to be performed"+
+						" is not to create the directory",
+					english.Join(onConflictParam.WhereSet(),
+						", ", " and "))
+			}
+
+			return nil
+		})
+
+		// The program name is not checked when searching recursively as
+		// the directory given is then only the root of the search.
+		ps.AddFinalCheck(func() error {
+			if prog.recursive || prog.dir == "" {
+				return nil
+			}
+
+			return checkProgName(prog.name)
+		})
+
+		ps.Add(paramNameTemplateDir,
+			psetter.Pathname{
+				Value: &prog.templateDirName,
+			},
 			"The name of the template directory"+
-				" from which to generate the program.",
+				" from which to generate the program. If this is"+
+				" given in a configuration file, a relative pathname"+
+				" is taken from the directory holding that file.",
 			param.AltNames("template-dir", "template"),
 			param.PostAction(
-				func(_ location.L, _ *param.BaseParam, _ []string) error {
+				func(loc location.L, _ *param.BaseParam, _ []string) error {
+					prog.templateDirName = configRelPath(ps, loc,
+						prog.templateDirName)
+
+					err := filecheck.Provisos{
+						Existence: filecheck.MustExist,
+						Checks:    []check.FileInfo{check.FileInfoIsDir},
+					}.StatusCheck(prog.templateDirName)
+					if err != nil {
+						return err
+					}
+
 					prog.templateFS = os.DirFS(prog.templateDirName)
 					prog.walkerBase = "."
+					prog.templateOnDisk = true
 
 					return nil
 				}),
@@ -138,12 +425,23 @@ func addParams(prog *Prog) param.PSetOptFunc {
 				" of the umask and so may be different from the"+
 				" given value."+
 				" Note also that directories are created with"+
-				" execute (search) permission set.",
+				" execute (search) permission set."+
+				"\n\n"+
+				"If the template directory is on disk and this"+
+				" parameter is not given then each file and directory"+
+				" is created with the permissions of the corresponding"+
+				" template file or directory. Template files with a"+
+				" permissions suffix ('"+sfxPerms+"' followed by the"+
+				" permissions in octal) are always created with the"+
+				" permissions given in the suffix.",
 			param.PostAction(
 				func(_ location.L, _ *param.BaseParam, _ []string) error {
 					prog.dirPerms = prog.filePerms | dirSearchPerms
+					prog.filePermsGiven = true
+
 					return nil
 				}),
+			param.SeeNote(noteNamePermsFiles),
 		)
 
 		ps.Add(paramNameCheckPerms,
@@ -155,9 +453,35 @@ func addParams(prog *Prog) param.PSetOptFunc {
 				" created with the umask applied and so may be"+
 				" different from the given value. This can mean that"+
 				" the created files etc do not have the given"+
-				" permissions (they may have fewer).",
+				" permissions (they may have fewer). You can change"+
+				" how the permissions are compared to allow for this.",
 			param.Attrs(param.CommandLineOnly),
 			param.AltNames("chk-perms"),
+			param.SeeAlso(paramNamePermsCheckMode),
+		)
+
+		ps.Add(paramNamePermsCheckMode,
+			psetter.Enum[permsCheckMode]{
+				Value: &prog.permsCheckMode,
+				AllowedVals: psetter.AllowedVals[permsCheckMode]{
+					pcmExact: "the actual permissions must exactly" +
+						" match the expected permissions.",
+					pcmUmask: "the process umask is applied to the" +
+						" expected permissions and the actual" +
+						" permissions must then match exactly.",
+					pcmNoWider: "the actual permissions must not" +
+						" include any permission that is not in the" +
+						" expected permissions. Fewer permissions" +
+						" are allowed.",
+				},
+			},
+			"How the permissions of the files and directories are"+
+				" compared with the expected permissions. Setting this"+
+				" will also turn on checking of the permissions.",
+			param.AltNames("chk-perms-mode"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheckPerms, paramNamePerms),
+			param.PostAction(paction.SetVal(&prog.checkPerms, true)),
 		)
 
 		reportAllParam := ps.Add(paramNameReportMissingOptFiles,
@@ -171,6 +495,289 @@ func addParams(prog *Prog) param.PSetOptFunc {
 			param.Attrs(param.CommandLineOnly),
 		)
 
+		checkBuildParam := ps.Add(paramNameCheckBuild,
+			psetter.Bool{
+				Value: &prog.checkBuild,
+			},
+			"After the files have been checked, load the program"+
+				" package, check that it builds and run the 'go vet'"+
+				" analyzers over it. Any type errors or problems found"+
+				" by the analyzers are reported. Only modules already in"+
+				" the local module cache are used, the network is not"+
+				" consulted.",
+			param.AltNames("chk-build"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheck, paramNameFix),
+		)
+
+		warnErrParam := ps.Add(paramNameWarningsAsErrors,
+			psetter.Bool{
+				Value: &prog.warningsAsErrors,
+			},
+			"Treat a failed content check with a severity of '"+
+				string(sevWarning)+"' as a problem, just as if it"+
+				" had a severity of '"+string(sevError)+"'. By default"+
+				" such failures are reported but do not cause the"+
+				" check to fail.",
+			param.AltNames("Werror"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheck, paramNameFix),
+			param.SeeNote(noteNameCheckFiles),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if warnErrParam.HasBeenSet() &&
+				prog.action != aCheck &&
+				prog.action != aFix &&
+				prog.action != aTestTemplate {
+				return fmt.Errorf(
+					"you have asked for warnings to be treated as"+
+						" errors (at %s) but the action to be performed"+
+						" is not to check or fix the directory"+
+						" or to test the template",
+					english.Join(warnErrParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
+		strictParam := ps.Add(paramNameStrict,
+			psetter.Bool{
+				Value: &prog.strict,
+			},
+			"After the files have been checked, also walk the"+
+				" target directory and report any files or"+
+				" directories which are not produced by the template."+
+				" Files which are expected even though the template"+
+				" does not produce them, such as additional source"+
+				" files, can be listed, in gitignore format, in a"+
+				" file called '"+strictIgnoreFileName+"' in the target"+
+				" directory. The .git directory, the Go module files"+
+				" and the undo journal are never reported.",
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheck, paramNameFix),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if strictParam.HasBeenSet() &&
+				prog.action != aCheck &&
+				prog.action != aFix &&
+				prog.action != aTestTemplate {
+				return fmt.Errorf(
+					"you have asked for a strict check (at %s)"+
+						" but the action to be performed"+
+						" is not to check or fix the directory"+
+						" or to test the template",
+					english.Join(strictParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
+		failFastParam := ps.Add(paramNameFailFast,
+			psetter.Bool{
+				Value: &prog.failFast,
+			},
+			"Stop at the first problem found. By default all the"+
+				" problems found walking the template directory, such"+
+				" as unreadable template files, bad check-type"+
+				" suffixes or directories that cannot be created, are"+
+				" collected and reported together once the walk is"+
+				" complete so that they can all be fixed at once."+
+				" Similarly, every content check is applied to each"+
+				" file and every file is checked. With this parameter"+
+				" the walk stops at the first problem, the checks of"+
+				" a file stop at the first failure and no more files"+
+				" are checked once a file has failed. Note that if"+
+				" more than one file is checked at once, the checks"+
+				" of those files already started are completed.",
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameJobs),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if failFastParam.HasBeenSet() &&
+				prog.action != aCreate &&
+				prog.action != aCheck &&
+				prog.action != aFix &&
+				prog.action != aTestTemplate {
+				return fmt.Errorf(
+					"you have asked to stop at the first problem (at %s)"+
+						" but the action to be performed"+
+						" is not to create, check or fix the directory"+
+						" or to test the template",
+					english.Join(failFastParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
+		quietParam := ps.Add(paramNameQuiet,
+			psetter.Bool{
+				Value: &prog.quiet,
+			},
+			"Do not report the results of checking the individual"+
+				" files or the program directory, only the summary of"+
+				" the numbers of files, directories and symbolic links"+
+				" checked, passed, fixed, failed and missing, the"+
+				" number of content checks run and the"+
+				" number of checks of the program directory as a whole"+
+				" that failed.",
+			param.AltNames("q"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheck, paramNameFix),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if quietParam.HasBeenSet() &&
+				prog.action != aCheck &&
+				prog.action != aFix &&
+				prog.action != aTestTemplate {
+				return fmt.Errorf(
+					"you have asked for only a summary (at %s)"+
+						" but the action to be performed"+
+						" is not to check or fix the directory"+
+						" or to test the template",
+					english.Join(quietParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
+		ps.Add(paramNameModulePath,
+			psetter.String[string]{
+				Value: &prog.modulePath,
+				Checks: []check.ValCk[string]{
+					module.CheckImportPath,
+				},
+			},
+			"The module path to use if a "+goModFileName+" file has to"+
+				" be generated. A "+goModFileName+" file is generated"+
+				" if the target directory is not already in a Go module"+
+				" and the template has generated Go files. The"+
+				" generated file will require those modules imported by"+
+				" the generated files at the versions given in the"+
+				" template's requirements files."+
+				"\n\n"+
+				"If this is not given the program name is used.",
+			param.AltNames("module", "mod-path"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeNote(noteNameRequiresFiles),
+		)
+
+		addToWorkspaceParam := ps.Add(paramNameAddToWorkspace,
+			psetter.Bool{
+				Value: &prog.addToWorkspace,
+			},
+			"After the target directory has been created, if it has"+
+				" its own "+goModFileName+" file, find the enclosing"+
+				" "+goWorkFileName+" file and add the target directory"+
+				" to its list of used directories. The use directives"+
+				" are kept in sorted order."+
+				"\n\n"+
+				"Note that when checking a target directory which has"+
+				" its own "+goModFileName+" file and which is in a"+
+				" workspace, it is reported if it is not used in the"+
+				" "+goWorkFileName+" file. Fixing the target directory"+
+				" will add it.",
+			param.AltNames("add-to-go-work", "add-to-work"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameModulePath),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if addToWorkspaceParam.HasBeenSet() &&
+				prog.action != aCreate {
+				return fmt.Errorf(
+					"you have asked for the target directory to be added"+
+						" to the workspace (at %s) but the action to be"+
+						" performed is not to create the directory",
+					english.Join(addToWorkspaceParam.WhereSet(),
+						", ", " and "))
+			}
+
+			return nil
+		})
+
+		recursiveParam := ps.Add(paramNameRecursive,
+			psetter.Bool{
+				Value: &prog.recursive,
+			},
+			"Rather than checking the single directory given by the"+
+				" '"+paramNameProgName+"' parameter, search that"+
+				" directory and all its sub-directories for program"+
+				" directories and check each of them. A program"+
+				" directory is one containing a '"+progDirMainFile+"'"+
+				" file which refers to '"+progDirMainMarker+"'."+
+				" Directories whose names start with '.' or '_' are not"+
+				" searched, nor are 'testdata' or 'vendor' directories."+
+				"\n\n"+
+				"After all the program directories have been checked, a"+
+				" summary of the results is shown. If any program"+
+				" directory fails its checks the program will exit with"+
+				" a non-zero status.",
+			param.AltNames("r", "check-tree"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameCheck, paramNameFix),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if recursiveParam.HasBeenSet() &&
+				prog.action == aCreate {
+				return fmt.Errorf(
+					"you have asked for a recursive check"+
+						" (at %s) but the action to be performed"+
+						" is still to create the directory",
+					english.Join(recursiveParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
+		ps.Add(paramNameJobs,
+			psetter.Int[int]{
+				Value: &prog.jobs,
+				Checks: []check.ValCk[int]{
+					check.ValGT(0),
+				},
+			},
+			"The maximum number of checks to run at the same time."+
+				" When checking a single program directory the files"+
+				" are checked in parallel, when checking recursively the"+
+				" program directories are checked in parallel. Whatever"+
+				" the order in which the checks complete, the results"+
+				" are reported in order of the path checked."+
+				"\n\n"+
+				"The default is the number of CPUs available.",
+			param.AltNames("j"),
+			param.SeeAlso(paramNameCheck, paramNameFix, paramNameRecursive),
+		)
+
+		ps.Add(paramNameNoHooks,
+			psetter.Bool{
+				Value:  &prog.runHooks,
+				Invert: true,
+			},
+			"Do not run any of the hook commands given in the template"+
+				" after the files have been created or fixed.",
+			param.AltNames("skip-hooks"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeNote(noteNameHooks),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if checkBuildParam.HasBeenSet() &&
+				prog.action == aCreate {
+				return fmt.Errorf(
+					"you have asked for the build to be checked"+
+						" (at %s) but the action to be performed"+
+						" is still to create the directory",
+					english.Join(checkBuildParam.WhereSet(), ", ", " and "))
+			}
+
+			return nil
+		})
+
 		ps.AddFinalCheck(func() error {
 			if reportAllParam.HasBeenSet() &&
 				prog.action == aCreate {
@@ -184,6 +791,22 @@ func addParams(prog *Prog) param.PSetOptFunc {
 			return nil
 		})
 
+		ps.AddFinalCheck(func() error {
+			// there is no single program directory when checking
+			// recursively and so no module path to check
+			if prog.recursive {
+				return nil
+			}
+
+			if prog.action != aCreate &&
+				prog.action != aCheck &&
+				prog.action != aFix {
+				return nil
+			}
+
+			return module.CheckImportPath(prog.goModulePath())
+		})
+
 		ps.AddFinalCheck(func() error {
 			if prog.dir == "" {
 				return nil
@@ -196,7 +819,14 @@ func addParams(prog *Prog) param.PSetOptFunc {
 				provisos = filecheck.Provisos{
 					Existence: filecheck.MustNotExist,
 				}
-			case aCheck, aFix:
+
+				if onConflictParam.HasBeenSet() {
+					provisos = filecheck.Provisos{
+						Existence: filecheck.Optional,
+						Checks:    []check.FileInfo{check.FileInfoIsDir},
+					}
+				}
+			case aCheck, aFix, aMakeTemplate, aUndo:
 				provisos = filecheck.Provisos{
 					Existence: filecheck.MustExist,
 					Checks:    []check.FileInfo{check.FileInfoIsDir},
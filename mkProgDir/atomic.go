@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// tmpNameSuffix is added to the names of the temporary files and
+// directories used to build the target before renaming it into place.
+const tmpNameSuffix = ".mkProgDir-tmp-"
+
+// tmpNamePattern returns the pattern for a temporary file or directory to
+// be renamed to the named file. The temporary name is hidden and in the
+// same directory so that the rename will not have to move it between file
+// systems.
+func tmpNamePattern(name string) string {
+	return "." + filepath.Base(name) + tmpNameSuffix + "*"
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory
+// as the named file and then renames it to the named file. This means that
+// the named file is either left unchanged or is completely written. The
+// file is given exactly the permissions passed, no umask is applied.
+func writeFileAtomic(name string, data []byte, perms os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), tmpNamePattern(name))
+	if err != nil {
+		return err
+	}
+
+	tmpName := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perms)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpName, name)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+
+	return err
+}
+
+// symlinkAtomic makes a symbolic link with a temporary name in the same
+// directory as the named link and then renames it to the named link. This
+// will replace any existing file but not a directory.
+func symlinkAtomic(linkTarget, name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), tmpNamePattern(name))
+	if err != nil {
+		return err
+	}
+
+	tmpName := f.Name()
+
+	// the temporary file is only used to reserve a unique name
+	_ = f.Close()
+	_ = os.Remove(tmpName)
+
+	err = os.Symlink(linkTarget, tmpName)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpName, name)
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+
+	return err
+}
+
+// makeParentDirs makes any missing directories above the target directory
+// and returns the directories it has made, outermost first. If any
+// directory cannot be made those already made are removed.
+func (prog *Prog) makeParentDirs() ([]string, error) {
+	missing := []string{}
+
+	for dir := filepath.Dir(prog.dir); ; dir = filepath.Dir(dir) {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		missing = append(missing, dir)
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	slices.Reverse(missing)
+
+	made := []string{}
+
+	for _, dir := range missing {
+		err := os.Mkdir(dir, prog.dirPerms)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+
+		if err != nil {
+			_ = removeDirs(made)
+			return nil, err
+		}
+
+		made = append(made, dir)
+	}
+
+	return made, nil
+}
+
+// removeDirs removes the directories, innermost first. It stops and
+// returns the error at the first directory that cannot be removed.
+func removeDirs(dirs []string) error {
+	for _, dir := range slices.Backward(dirs) {
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// makeBuildDir makes the temporary directory in which the target directory
+// will be built. It is made in the same directory as the target directory
+// and is given the directory permissions. Any missing directories above
+// the target directory are made and returned so that they can be removed
+// if the target directory is not created.
+func (prog *Prog) makeBuildDir() (string, []string, error) {
+	parents, err := prog.makeParentDirs()
+	if err != nil {
+		return "", nil, err
+	}
+
+	buildDir, err := os.MkdirTemp(filepath.Dir(prog.dir),
+		tmpNamePattern(prog.dir))
+	if err != nil {
+		_ = removeDirs(parents)
+		return "", nil, err
+	}
+
+	err = os.Chmod(buildDir, prog.dirPerms&^prog.umask)
+	if err != nil {
+		_ = os.RemoveAll(buildDir)
+		_ = removeDirs(parents)
+
+		return "", nil, err
+	}
+
+	return buildDir, parents, nil
+}
+
+// moveTargets changes the recorded targets from the old directory to the
+// new directory
+func (prog *Prog) moveTargets(oldDir, newDir string) {
+	for i, target := range prog.targets {
+		rel, found := strings.CutPrefix(target, oldDir)
+		if found {
+			prog.targets[i] = newDir + rel
+		}
+	}
+}
+
+// buildTargetDir populates the target directory from the template by
+// building it in a temporary directory and then renaming that to the
+// target directory. If anything goes wrong the temporary directory and any
+// directories made above it are removed and the target directory is not
+// created.
+func (prog *Prog) buildTargetDir() {
+	defer prog.stack.Start("buildTargetDir", "Start")()
+
+	targetDir := prog.dir
+
+	buildDir, parents, err := prog.makeBuildDir()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot create the program directory (%q): %s\n", targetDir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.dir = buildDir
+
+	prog.journalAfter(buildDir, nil)
+	prog.CreateAllFiles()
+
+	if prog.exitStatus == 0 {
+		prog.CreateGoModIfNeeded()
+	}
+
+	prog.dir = targetDir
+
+	if prog.exitStatus == 0 {
+		err = os.Rename(buildDir, targetDir)
+		if err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot rename the temporary directory (%q) to %q: %s\n",
+				buildDir, targetDir, err)
+			prog.SetExitStatus(esIOError)
+		}
+	}
+
+	if prog.exitStatus != 0 {
+		if err := os.RemoveAll(buildDir); err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot remove the temporary directory (%q): %s\n",
+				buildDir, err)
+		}
+
+		if err := removeDirs(parents); err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot remove the directories made for %q: %s\n",
+				targetDir, err)
+		}
+
+		prog.journal = &journal{}
+
+		fmt.Fprintf(prog.out,
+			"The program directory (%q) has not been created\n", targetDir)
+
+		return
+	}
+
+	prog.moveTargets(buildDir, targetDir)
+}
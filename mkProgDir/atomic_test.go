@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// dirEntryNames returns the names of the entries in the directory
+func dirEntryNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read the directory %q: %s", dir, err)
+	}
+
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	return names
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "f.txt")
+
+	for _, content := range []string{"first", "second"} {
+		err := writeFileAtomic(name, []byte(content), 0o640)
+		if err != nil {
+			t.Fatalf("unexpected error writing %q: %s", name, err)
+		}
+
+		b, err := os.ReadFile(name) //nolint:gosec
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", name, err)
+		}
+
+		testhelper.DiffString(t, content, "contents", string(b), content)
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("cannot stat %q: %s", name, err)
+	}
+
+	testhelper.DiffInt(t, "writeFileAtomic", "perms",
+		int(fi.Mode().Perm()), 0o640)
+	testhelper.DiffStringSlice(t, "writeFileAtomic", "directory entries",
+		dirEntryNames(t, dir), []string{"f.txt"})
+}
+
+func TestSymlinkAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "link")
+
+	err := os.WriteFile(name, []byte("not a link"), 0o600)
+	if err != nil {
+		t.Fatalf("cannot write %q: %s", name, err)
+	}
+
+	err = symlinkAtomic("target", name)
+	if err != nil {
+		t.Fatalf("unexpected error making the link %q: %s", name, err)
+	}
+
+	linkTarget, err := os.Readlink(name)
+	if err != nil {
+		t.Fatalf("cannot read the link %q: %s", name, err)
+	}
+
+	testhelper.DiffString(t, "symlinkAtomic", "link target",
+		linkTarget, "target")
+	testhelper.DiffStringSlice(t, "symlinkAtomic", "directory entries",
+		dirEntryNames(t, dir), []string{"link"})
+}
+
+func TestCreateTargetDirFailure(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		dirParts []string
+	}{
+		{
+			ID:       testhelper.MkID("parent exists"),
+			dirParts: []string{"prog"},
+		},
+		{
+			ID:       testhelper.MkID("parents missing"),
+			dirParts: []string{"a", "b", "prog"},
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		parent := t.TempDir()
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = filepath.Join(append([]string{parent}, tc.dirParts...)...)
+		prog.name = "prog"
+		prog.templateFS = fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+			"b.txt" + sfxGenerate: &fstest.MapFile{
+				Data: []byte("${Undefined}"),
+			},
+		}
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CreateTargetDir()
+
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esTemplate)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "directory entries",
+			dirEntryNames(t, parent), []string{})
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/appends"
+	"golang.org/x/tools/go/analysis/passes/asmdecl"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/cgocall"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/defers"
+	"golang.org/x/tools/go/analysis/passes/directive"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/framepointer"
+	"golang.org/x/tools/go/analysis/passes/hostport"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/slog"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stdversion"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/timeformat"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/analysis/passes/waitgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// vetAnalyzers is the set of analyzers run when checking the build. It is
+// the same set as is run by 'go vet'.
+var vetAnalyzers = []*analysis.Analyzer{
+	appends.Analyzer,
+	asmdecl.Analyzer,
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	buildtag.Analyzer,
+	cgocall.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	defers.Analyzer,
+	directive.Analyzer,
+	errorsas.Analyzer,
+	framepointer.Analyzer,
+	hostport.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	loopclosure.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	printf.Analyzer,
+	shift.Analyzer,
+	sigchanyzer.Analyzer,
+	slog.Analyzer,
+	stdmethods.Analyzer,
+	stdversion.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	testinggoroutine.Analyzer,
+	tests.Analyzer,
+	timeformat.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+	waitgroup.Analyzer,
+}
+
+// buildProblem records a problem found while building the target package
+type buildProblem struct {
+	file   string
+	line   int
+	column int
+	msg    string
+}
+
+// String returns a string describing the problem in the conventional
+// file:line:column format
+func (bp buildProblem) String() string {
+	if bp.file == "" {
+		return bp.msg
+	}
+
+	if bp.line == 0 {
+		return fmt.Sprintf("%s: %s", bp.file, bp.msg)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", bp.file, bp.line, bp.column, bp.msg)
+}
+
+// cmpBuildProblems compares two build problems, ordering them by file, line,
+// column and message
+func cmpBuildProblems(a, b buildProblem) int {
+	return cmp.Or(
+		cmp.Compare(a.file, b.file),
+		cmp.Compare(a.line, b.line),
+		cmp.Compare(a.column, b.column),
+		cmp.Compare(a.msg, b.msg))
+}
+
+// targetName converts the file name (as given by the packages loader) into
+// a name relative to the target directory, as used for the template
+// targets. The absDir parameter should be the absolute path of the target
+// directory.
+func (prog *Prog) targetName(absDir, fName string) string {
+	rel, err := filepath.Rel(absDir, fName)
+	if err != nil || !filepath.IsLocal(rel) {
+		return fName
+	}
+
+	return filepath.Join(prog.dir, rel)
+}
+
+// loadTargetPackages loads the packages in the target directory (including
+// any test packages) with full syntax and type information. The Go command
+// is prevented from using the network so only modules already in the local
+// module cache can be used.
+func (prog *Prog) loadTargetPackages() ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   prog.dir,
+		Tests: true,
+		Env:   append(os.Environ(), "GOPROXY=off", "GOTOOLCHAIN=local"),
+	}
+
+	return packages.Load(cfg, ".")
+}
+
+// parsePosn parses the position string, as given in a packages.Error, into
+// its parts. The position string is of the form "file:line:column" where
+// the line and column are optional. An empty or unknown position (given as
+// "-") will give an empty Position.
+func parsePosn(pos string) token.Position {
+	posn := token.Position{}
+
+	if pos == "" || pos == "-" {
+		return posn
+	}
+
+	posn.Filename = pos
+
+	for _, num := range []*int{&posn.Column, &posn.Line} {
+		idx := strings.LastIndex(posn.Filename, ":")
+		if idx < 0 {
+			break
+		}
+
+		n, err := strconv.Atoi(posn.Filename[idx+1:])
+		if err != nil {
+			break
+		}
+
+		*num = n
+		posn.Filename = posn.Filename[:idx]
+	}
+
+	if posn.Line == 0 && posn.Column != 0 {
+		posn.Line, posn.Column = posn.Column, 0
+	}
+
+	return posn
+}
+
+// loadProblems returns any errors found while loading the packages
+func (prog *Prog) loadProblems(absDir string,
+	pkgs []*packages.Package,
+) []buildProblem {
+	problems := []buildProblem{}
+
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		for _, e := range p.Errors {
+			bp := buildProblem{msg: e.Msg}
+
+			if posn := parsePosn(e.Pos); posn.Filename != "" {
+				bp.file = prog.targetName(absDir, posn.Filename)
+				bp.line = posn.Line
+				bp.column = posn.Column
+			}
+
+			if !slices.Contains(problems, bp) {
+				problems = append(problems, bp)
+			}
+		}
+	})
+
+	return problems
+}
+
+// vetProblems runs the vet analyzers over the packages and returns any
+// problems found
+func (prog *Prog) vetProblems(absDir string,
+	pkgs []*packages.Package,
+) ([]buildProblem, error) {
+	graph, err := checker.Analyze(vetAnalyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []buildProblem{}
+
+	for act := range graph.All() {
+		if !act.IsRoot {
+			continue
+		}
+
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %w", act, act.Err)
+		}
+
+		for _, d := range act.Diagnostics {
+			posn := act.Package.Fset.Position(d.Pos)
+			bp := buildProblem{
+				file:   prog.targetName(absDir, posn.Filename),
+				line:   posn.Line,
+				column: posn.Column,
+				msg:    act.Analyzer.Name + ": " + d.Message,
+			}
+
+			// the test variant of a package will report the same problems
+			// as the package itself so we skip any duplicates
+			if !slices.Contains(problems, bp) {
+				problems = append(problems, bp)
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// reportBuildProblems reports the problems, grouped by file
+func (prog *Prog) reportBuildProblems(desc string, problems []buildProblem) {
+	slices.SortFunc(problems, cmpBuildProblems)
+
+	lastFile := ""
+
+	for i, bp := range problems {
+		if i == 0 || bp.file != lastFile {
+			name := bp.file
+			if name == "" {
+				name = "the program"
+			}
+
+			fmt.Fprintf(prog.out, "%q %s\n", name, desc)
+
+			lastFile = bp.file
+		}
+
+		fmt.Fprintf(prog.out, "\t%s\n", bp)
+	}
+}
+
+// CheckBuild loads the package in the target directory and reports any
+// errors found while type-checking it. If there are no such errors the vet
+// analyzers are run over the package and any problems they find are
+// reported.
+func (prog *Prog) CheckBuild() {
+	defer prog.stack.Start("CheckBuild", "Start")()
+
+	intro := prog.stack.Tag()
+
+	absDir, err := filepath.Abs(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot find the absolute path of %q: %s\n", prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	pkgs, err := prog.loadTargetPackages()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot load the program package (%q): %s\n", prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %d\n", intro, "packages loaded", len(pkgs))
+
+	if problems := prog.loadProblems(absDir, pkgs); len(problems) > 0 {
+		prog.reportBuildProblems("does not build", problems)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "package builds")
+
+	problems, err := prog.vetProblems(absDir, pkgs)
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot vet the program package (%q): %s\n", prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if len(problems) > 0 {
+		prog.reportBuildProblems("has vet findings", problems)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "vet OK")
+}
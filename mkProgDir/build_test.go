@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParsePosn(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		pos     string
+		expPosn token.Position
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:  testhelper.MkID("unknown"),
+			pos: "-",
+		},
+		{
+			ID:      testhelper.MkID("file only"),
+			pos:     "/a/b/c.go",
+			expPosn: token.Position{Filename: "/a/b/c.go"},
+		},
+		{
+			ID:      testhelper.MkID("file and line"),
+			pos:     "/a/b/c.go:12",
+			expPosn: token.Position{Filename: "/a/b/c.go", Line: 12},
+		},
+		{
+			ID:  testhelper.MkID("file, line and column"),
+			pos: "/a/b/c.go:12:3",
+			expPosn: token.Position{
+				Filename: "/a/b/c.go",
+				Line:     12,
+				Column:   3,
+			},
+		},
+		{
+			ID:      testhelper.MkID("file with a colon in the name"),
+			pos:     "c:d.go:12",
+			expPosn: token.Position{Filename: "c:d.go", Line: 12},
+		},
+	}
+
+	for _, tc := range testCases {
+		posn := parsePosn(tc.pos)
+		testhelper.DiffString(t, tc.IDStr(), "filename",
+			posn.Filename, tc.expPosn.Filename)
+		testhelper.DiffInt(t, tc.IDStr(), "line", posn.Line, tc.expPosn.Line)
+		testhelper.DiffInt(t, tc.IDStr(), "column",
+			posn.Column, tc.expPosn.Column)
+	}
+}
+
+func TestCheckBuild(t *testing.T) {
+	const goMod = "module example.com/prog\n\ngo 1.22\n"
+
+	testCases := []struct {
+		testhelper.ID
+		mainGo        string
+		expOut        string
+		expExitStatus int
+	}{
+		{
+			ID: testhelper.MkID("clean"),
+			mainGo: "package main\n\n" +
+				"import \"fmt\"\n\n" +
+				"func main() {\n\tfmt.Println(\"hello\")\n}\n",
+		},
+		{
+			ID: testhelper.MkID("type error"),
+			mainGo: "package main\n\n" +
+				"func main() {\n\tx := 1\n}\n",
+			expOut: `"prog/main.go" does not build` + "\n" +
+				"\tprog/main.go:4:2: declared and not used: x\n",
+			expExitStatus: esContent,
+		},
+		{
+			ID: testhelper.MkID("vet finding"),
+			mainGo: "package main\n\n" +
+				"import \"fmt\"\n\n" +
+				"func main() {\n\tfmt.Printf(\"%d\\n\", \"s\")\n}\n",
+			expOut: `"prog/main.go" has vet findings` + "\n" +
+				"\tprog/main.go:6:14: printf: fmt.Printf format %d" +
+				` has arg "s" of wrong type string` + "\n",
+			expExitStatus: esContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		if err := os.Mkdir("prog", 0o755); err != nil {
+			t.Fatalf("cannot make the program directory: %s", err)
+		}
+
+		for name, contents := range map[string]string{
+			"go.mod":  goMod,
+			"main.go": tc.mainGo,
+		} {
+			err := os.WriteFile(filepath.Join("prog", name),
+				[]byte(contents), 0o644)
+			if err != nil {
+				t.Fatalf("cannot write %q: %s", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+
+		prog.CheckBuild()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
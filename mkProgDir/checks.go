@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 )
@@ -53,7 +54,7 @@ var checkTypes = []checkTypeInfo{
 	},
 }
 
-type checkContentFunc func(string, string) int
+type checkContentFunc func(io.Writer, string, string) int
 
 type checkContentFuncMaker func(string) checkContentFunc
 
@@ -69,7 +70,7 @@ var checkTypeMap = map[string]checkContentFuncMaker{
 // checkContentBegins returns a function that checks that the contents begin
 // with the supplied value
 func checkContentBegins(begins string) checkContentFunc {
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if strings.HasPrefix(contents, begins) {
 			return 0
 		}
@@ -83,9 +84,9 @@ func checkContentBegins(begins string) checkContentFunc {
 			s = s[:maxContentToShow] + "..."
 		}
 
-		fmt.Printf("%q has unexpected content\n", path)
-		fmt.Printf("\tit should start with:\n%s\n", begins)
-		fmt.Printf("\tactually starts with:\n%s\n", s)
+		fmt.Fprintf(w, "%q has unexpected content\n", path)
+		fmt.Fprintf(w, "\tit should start with:\n%s\n", begins)
+		fmt.Fprintf(w, "\tactually starts with:\n%s\n", s)
 
 		return 1
 	}
@@ -94,7 +95,7 @@ func checkContentBegins(begins string) checkContentFunc {
 // checkContentEnds returns a function that checks that the contents begin
 // with the supplied value
 func checkContentEnds(ends string) checkContentFunc {
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if strings.HasSuffix(contents, ends) {
 			return 0
 		}
@@ -108,9 +109,9 @@ func checkContentEnds(ends string) checkContentFunc {
 			e = "..." + e[len(e)-maxContentToShow-1:]
 		}
 
-		fmt.Printf("%q has unexpected content\n", path)
-		fmt.Printf("\tit should end with:\n%s\n", ends)
-		fmt.Printf("\tactually ends with:\n%s\n", e)
+		fmt.Fprintf(w, "%q has unexpected content\n", path)
+		fmt.Fprintf(w, "\tit should end with:\n%s\n", ends)
+		fmt.Fprintf(w, "\tactually ends with:\n%s\n", e)
 
 		return 1
 	}
@@ -119,13 +120,13 @@ func checkContentEnds(ends string) checkContentFunc {
 // checkContentContains returns a function that checks that the contents
 // contain the supplied value
 func checkContentContains(contains string) checkContentFunc {
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if strings.Contains(contents, contains) {
 			return 0
 		}
 
-		fmt.Printf("%q has unexpected content\n", path)
-		fmt.Printf("\tdoes not contain:\n%s\n", contains)
+		fmt.Fprintf(w, "%q has unexpected content\n", path)
+		fmt.Fprintf(w, "\tdoes not contain:\n%s\n", contains)
 
 		return 1
 	}
@@ -134,10 +135,10 @@ func checkContentContains(contains string) checkContentFunc {
 // checkContentDoesNotContain returns a function that checks that the contents
 // do not contain the supplied value
 func checkContentDoesNotContain(contains string) checkContentFunc {
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if strings.Contains(contents, contains) {
-			fmt.Printf("%q has unexpected content\n", path)
-			fmt.Printf("\tcontains:\n%s\n", contains)
+			fmt.Fprintf(w, "%q has unexpected content\n", path)
+			fmt.Fprintf(w, "\tcontains:\n%s\n", contains)
 
 			return 1
 		}
@@ -151,13 +152,13 @@ func checkContentDoesNotContain(contains string) checkContentFunc {
 func checkContentMatches(reStr string) checkContentFunc {
 	re := regexp.MustCompile(reStr)
 
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if len(re.FindStringSubmatch(contents)) > 0 {
 			return 0
 		}
 
-		fmt.Printf("%q has unexpected content\n", path)
-		fmt.Printf("\tdoes not match:\n%s\n", re)
+		fmt.Fprintf(w, "%q has unexpected content\n", path)
+		fmt.Fprintf(w, "\tdoes not match:\n%s\n", re)
 
 		return 1
 	}
@@ -169,10 +170,10 @@ func checkContentMatches(reStr string) checkContentFunc {
 func checkContentDoesNotMatch(reStr string) checkContentFunc {
 	re := regexp.MustCompile(reStr)
 
-	return func(path, contents string) int {
+	return func(w io.Writer, path, contents string) int {
 		if len(re.FindStringSubmatch(contents)) > 0 {
-			fmt.Printf("%q has unexpected content\n", path)
-			fmt.Printf("\tmatches:\n%s\n", re)
+			fmt.Fprintf(w, "%q has unexpected content\n", path)
+			fmt.Fprintf(w, "\tmatches:\n%s\n", re)
 
 			return 1
 		}
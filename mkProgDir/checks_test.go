@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"testing"
 
 	"github.com/nickwells/testhelper.mod/v2/testhelper"
@@ -177,7 +178,7 @@ end
 				continue
 			}
 
-			ccFuncRval := ccFunc(testPath, testText)
+			ccFuncRval := ccFunc(os.Stdout, testPath, testText)
 			testhelper.DiffInt(t,
 				tc.IDStr(), "check func return value",
 				ccFuncRval, tc.expRval)
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/filecheck.mod/filecheck"
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/xdg.mod/xdg"
+)
+
+const (
+	// projectConfigFileName is the name of the per-project configuration
+	// file. It is found by searching upwards from the target directory.
+	projectConfigFileName = ".mkProgDir"
+	// userConfigFileName is the name of the per-user configuration file in
+	// the program's directory under the XDG config directory
+	userConfigFileName = "common.cfg"
+)
+
+// progNameParamNames lists the name and the alternative names of the
+// parameter giving the program name (and so the target directory)
+var progNameParamNames = []string{paramNameProgName, "prog-name", "name"}
+
+// userConfigFile returns the name of the per-user configuration file. It
+// returns an empty string if the XDG config directory cannot be found.
+func userConfigFile() string {
+	baseDir := xdg.ConfigHome()
+	if baseDir == "" {
+		return ""
+	}
+
+	return filepath.Join(baseDir,
+		"github.com",
+		"nickwells",
+		"progtools",
+		"mkProgDir",
+		userConfigFileName)
+}
+
+// targetDirFromArgs returns the target directory given by the program name
+// parameter in the arguments. The configuration files are read before the
+// command line is parsed and so the arguments must be searched for it. If
+// the program name is not given the current directory is returned.
+func targetDirFromArgs(args []string) string {
+	dir := "."
+
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name, val, hasVal := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !slices.Contains(progNameParamNames, name) {
+			continue
+		}
+
+		if hasVal {
+			dir = val
+		} else if i+1 < len(args) {
+			dir = args[i+1]
+		}
+	}
+
+	return dir
+}
+
+// configRelPath returns the pathname taken relative to the directory
+// holding the configuration file if the location is in one of the
+// configuration files and the pathname is relative. Otherwise the pathname
+// is returned unchanged.
+func configRelPath(ps *param.PSet, loc location.L, pathname string) string {
+	if filepath.IsAbs(pathname) {
+		return pathname
+	}
+
+	for _, cf := range ps.ConfigFiles() {
+		if cf.Name == loc.Source() {
+			return filepath.Join(filepath.Dir(cf.Name), pathname)
+		}
+	}
+
+	return pathname
+}
+
+// addConfigFiles adds the configuration files to the param set. The
+// per-user file is read first and then the per-project file, if there is
+// one, so that the project settings take precedence. Parameters given on
+// the command line take precedence over both.
+func addConfigFiles(args []string) param.PSetOptFunc {
+	return func(ps *param.PSet) error {
+		if name := userConfigFile(); name != "" {
+			ps.AddConfigFileStrict(name, filecheck.Optional)
+		}
+
+		name, err := findFileUpwards(targetDirFromArgs(args),
+			projectConfigFileName)
+		if err != nil {
+			ps.AddErr(param.SrcConfigFilePfx,
+				fmt.Errorf("cannot search for the %q file: %w",
+					projectConfigFileName, err))
+
+			return nil
+		}
+
+		if name != "" {
+			ps.AddConfigFileStrict(name, filecheck.MustExist)
+		}
+
+		return nil
+	}
+}
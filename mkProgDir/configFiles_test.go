@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/param.mod/v7/paramset"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTargetDirFromArgs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		args   []string
+		expDir string
+	}{
+		{
+			ID:     testhelper.MkID("no args"),
+			expDir: ".",
+		},
+		{
+			ID:     testhelper.MkID("name not given"),
+			args:   []string{"-check", "-q"},
+			expDir: ".",
+		},
+		{
+			ID:     testhelper.MkID("separate value"),
+			args:   []string{"-check", "-name", "a/b"},
+			expDir: "a/b",
+		},
+		{
+			ID:     testhelper.MkID("joined value"),
+			args:   []string{"--program-name=a/b", "-check"},
+			expDir: "a/b",
+		},
+		{
+			ID:     testhelper.MkID("last value used"),
+			args:   []string{"-prog-name", "a", "-name=b"},
+			expDir: "b",
+		},
+		{
+			ID:     testhelper.MkID("after the terminal param"),
+			args:   []string{"-check", "--", "-name", "a"},
+			expDir: ".",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "target dir",
+			targetDirFromArgs(tc.args), tc.expDir)
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "cfg"))
+
+	userCfgFile := userConfigFile()
+	if err := os.MkdirAll(filepath.Dir(userCfgFile), 0o755); err != nil {
+		t.Fatalf("cannot make the user config directory: %s", err)
+	}
+
+	if err := os.WriteFile(userCfgFile,
+		[]byte("report-format=table\npermissions=0600\n"),
+		0o600); err != nil {
+		t.Fatalf("cannot write %q: %s", userCfgFile, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join("proj", "sub"), 0o755); err != nil {
+		t.Fatalf("cannot make the project directory: %s", err)
+	}
+
+	cfgFile := filepath.Join("proj", projectConfigFileName)
+	if err := os.WriteFile(cfgFile, []byte("report-format=json\n"),
+		0o600); err != nil {
+		t.Fatalf("cannot write %q: %s", cfgFile, err)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		args      []string
+		expFormat reportFormat
+	}{
+		{
+			ID: testhelper.MkID("in a project sub-directory"),
+			args: []string{
+				"-name", "proj/sub/prog", "-action", "describe-template",
+			},
+			expFormat: rfJSON,
+		},
+		{
+			ID: testhelper.MkID("new project directory"),
+			args: []string{
+				"-name", "proj/new", "-action", "describe-template",
+			},
+			expFormat: rfJSON,
+		},
+		{
+			ID: testhelper.MkID("not in the project"),
+			args: []string{
+				"-name", "other", "-action", "describe-template",
+			},
+			expFormat: rfTable,
+		},
+		{
+			ID: testhelper.MkID("command line"),
+			args: []string{
+				"-name", "proj/new", "-action", "describe-template",
+				"-report-format", "table",
+			},
+			expFormat: rfTable,
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := NewProg()
+		ps := paramset.New(addParams(prog), addNotes(prog),
+			addConfigFiles(tc.args))
+		ps.Parse(tc.args)
+
+		testhelper.DiffString(t, tc.IDStr(), "report format",
+			string(prog.reportFormat), string(tc.expFormat))
+		testhelper.DiffInt(t, tc.IDStr(), "permissions",
+			int(prog.filePerms), 0o600)
+	}
+}
+
+func TestConfigFileRelativePath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "cfg"))
+
+	for _, dir := range []string{
+		filepath.Join("proj", "tmpl"),
+		filepath.Join("proj", "sub", "tmpl"),
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("cannot make %q: %s", dir, err)
+		}
+	}
+
+	cfgFile := filepath.Join("proj", projectConfigFileName)
+	if err := os.WriteFile(cfgFile, []byte("template-directory=tmpl\n"),
+		0o600); err != nil {
+		t.Fatalf("cannot write %q: %s", cfgFile, err)
+	}
+
+	expDir, err := filepath.Abs(filepath.Join("proj", "tmpl"))
+	if err != nil {
+		t.Fatalf("cannot get the template directory: %s", err)
+	}
+
+	t.Chdir(filepath.Join("proj", "sub"))
+
+	args := []string{"-name", "prog", "-action", "describe-template"}
+
+	prog := NewProg()
+	ps := paramset.New(addParams(prog), addNotes(prog), addConfigFiles(args))
+	ps.Parse(args)
+
+	testhelper.DiffString(t, "from a sub-directory", "template directory",
+		prog.templateDirName, expDir)
+}
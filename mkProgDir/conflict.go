@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+type conflictPolicy string
+
+const (
+	ocFail           = conflictPolicy("fail")
+	ocSkip           = conflictPolicy("skip")
+	ocOverwrite      = conflictPolicy("overwrite")
+	ocBackup         = conflictPolicy("backup")
+	ocWriteAlongside = conflictPolicy("write-alongside")
+)
+
+const (
+	sfxBackup = ".mkProgDir-backup"
+	sfxNew    = ".mkProgDir-new"
+)
+
+// unusedName returns the name if nothing exists with that name, otherwise
+// it adds a numeric suffix and returns the first such name that is not in
+// use.
+func unusedName(name string) (string, error) {
+	newName := name
+
+	for i := 1; ; i++ {
+		_, err := os.Lstat(newName)
+		if errors.Is(err, fs.ErrNotExist) {
+			return newName, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		newName = name + "." + strconv.Itoa(i)
+	}
+}
+
+// isADir returns true if the path exists and is a directory
+func isADir(path string) bool {
+	fi, err := os.Stat(path)
+
+	return err == nil && fi.IsDir()
+}
+
+// conflictsWith returns true if the target already exists and so the
+// template file would conflict with it. An existing directory does not
+// conflict with a template directory. An error is returned if the target
+// cannot be checked.
+func conflictsWith(tfi TemplateFileInfo) (bool, fs.FileInfo, error) {
+	fi, err := os.Lstat(tfi.target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil, nil
+	}
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	if tfi.isADir && fi.IsDir() {
+		return false, fi, nil
+	}
+
+	return true, fi, nil
+}
+
+// findConflicts returns the targets that already exist and would conflict
+// with the template files.
+func (prog *Prog) findConflicts() ([]string, error) {
+	conflicts := []string{}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			tfi, err := prog.getTemplateFileInfo(path, d)
+			if err != nil {
+				return err
+			}
+
+			if tfi.isTheTemplateDir ||
+				tfi.isACheckFile ||
+				tfi.isARequiresFile ||
+				tfi.isAHook {
+				return nil
+			}
+
+			clash, _, err := conflictsWith(tfi)
+			if err != nil {
+				return err
+			}
+
+			if clash {
+				conflicts = append(conflicts, tfi.target)
+			}
+
+			return nil
+		})
+
+	return conflicts, err
+}
+
+// resolveConflict applies the conflict policy to the target if it already
+// exists. It returns true if the target should then be created together
+// with a message to be shown once it has been. The target to be created
+// may have been changed. It returns false if the target is a directory
+// which already exists, there is then nothing to be done.
+func (prog *Prog) resolveConflict(tfi *TemplateFileInfo) (bool, string) {
+	conflicts, fi, err := conflictsWith(*tfi)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot check %q: %s\n", tfi.target, err)
+		prog.SetExitStatus(esIOError)
+
+		return false, ""
+	}
+
+	if !conflicts {
+		return fi == nil, ""
+	}
+
+	switch prog.onConflict {
+	case ocSkip:
+		fmt.Fprintf(prog.out, "%q already exists, it has been skipped\n",
+			tfi.target)
+
+		return false, ""
+	case ocBackup:
+		backup, err := unusedName(tfi.target + sfxBackup)
+		if err == nil {
+			err = os.Rename(tfi.target, backup)
+		}
+
+		if err != nil {
+			fmt.Fprintf(prog.out, "Cannot back up %q: %s\n", tfi.target, err)
+			prog.SetExitStatus(esIOError)
+
+			return false, ""
+		}
+
+		prog.journalMoved(tfi.target, backup)
+
+		return true, fmt.Sprintf(
+			"%q already existed, it has been moved to %q\n",
+			tfi.target, backup)
+	case ocOverwrite, ocWriteAlongside:
+		if tfi.isADir || fi.IsDir() {
+			fmt.Fprintf(prog.out,
+				"%q already exists, a directory cannot be replaced"+
+					" or written alongside\n", tfi.target)
+			prog.SetExitStatus(esFailure)
+
+			return false, ""
+		}
+
+		if prog.onConflict == ocOverwrite {
+			return true, fmt.Sprintf(
+				"%q already existed, it has been overwritten\n", tfi.target)
+		}
+
+		newName, err := unusedName(tfi.target + sfxNew)
+		if err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot find a name to write %q alongside: %s\n",
+				tfi.target, err)
+			prog.SetExitStatus(esIOError)
+
+			return false, ""
+		}
+
+		existing := tfi.target
+		tfi.target = newName
+
+		return true, fmt.Sprintf(
+			"%q already exists, the new file has been written to %q\n",
+			existing, tfi.target)
+	}
+
+	fmt.Fprintf(prog.out, "%q already exists\n", tfi.target)
+	prog.SetExitStatus(esFailure)
+
+	return false, ""
+}
+
+// createInExistingDir populates the existing target directory from the
+// template, applying the conflict policy to any targets that already
+// exist. If the policy is to fail then the existing targets are reported
+// and nothing is created.
+func (prog *Prog) createInExistingDir() {
+	defer prog.stack.Start("createInExistingDir", "Start")()
+
+	if prog.onConflict == ocFail {
+		conflicts, err := prog.findConflicts()
+		if err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot check the program directory (%q): %s\n",
+				prog.dir, err)
+			prog.SetExitStatus(esIOError)
+
+			return
+		}
+
+		if len(conflicts) > 0 {
+			for _, c := range conflicts {
+				fmt.Fprintf(prog.out, "%q already exists\n", c)
+			}
+
+			fmt.Fprintf(prog.out,
+				"The program directory (%q) has not been changed\n",
+				prog.dir)
+			prog.SetExitStatus(esFailure)
+
+			return
+		}
+	}
+
+	prog.CreateAllFiles()
+
+	if prog.exitStatus == 0 {
+		prog.CreateGoModIfNeeded()
+	}
+}
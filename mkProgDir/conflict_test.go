@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCreateInExistingDir(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		policy        conflictPolicy
+		extraFiles    map[string]string
+		expOut        string
+		expExitStatus int
+		expFiles      map[string]string
+	}{
+		{
+			ID:     testhelper.MkID("fail"),
+			policy: ocFail,
+			expOut: `"prog/a.txt" already exists` + "\n" +
+				`The program directory ("prog") has not been changed` + "\n",
+			expExitStatus: esFailure,
+			expFiles: map[string]string{
+				"a.txt":       "old",
+				"b/other.txt": "other",
+			},
+		},
+		{
+			ID:     testhelper.MkID("skip"),
+			policy: ocSkip,
+			expOut: `"prog/a.txt" already exists, it has been skipped` + "\n",
+			expFiles: map[string]string{
+				"a.txt":       "old",
+				"b/c.txt":     "c",
+				"new.txt":     "new",
+				"b/other.txt": "other",
+			},
+		},
+		{
+			ID:     testhelper.MkID("overwrite"),
+			policy: ocOverwrite,
+			expOut: `"prog/a.txt" already existed,` +
+				` it has been overwritten` + "\n",
+			expFiles: map[string]string{
+				"a.txt":       "a",
+				"b/c.txt":     "c",
+				"new.txt":     "new",
+				"b/other.txt": "other",
+			},
+		},
+		{
+			ID:     testhelper.MkID("backup"),
+			policy: ocBackup,
+			expOut: `"prog/a.txt" already existed,` +
+				` it has been moved to "prog/a.txt.mkProgDir-backup"` + "\n",
+			expFiles: map[string]string{
+				"a.txt":                  "a",
+				"a.txt.mkProgDir-backup": "old",
+				"b/c.txt":                "c",
+				"new.txt":                "new",
+				"b/other.txt":            "other",
+			},
+		},
+		{
+			ID:     testhelper.MkID("write-alongside"),
+			policy: ocWriteAlongside,
+			expOut: `"prog/a.txt" already exists,` +
+				` the new file has been written to` +
+				` "prog/a.txt.mkProgDir-new"` + "\n",
+			expFiles: map[string]string{
+				"a.txt":               "old",
+				"a.txt.mkProgDir-new": "a",
+				"b/c.txt":             "c",
+				"new.txt":             "new",
+				"b/other.txt":         "other",
+			},
+		},
+		{
+			ID:         testhelper.MkID("write-alongside, new file exists"),
+			policy:     ocWriteAlongside,
+			extraFiles: map[string]string{"prog/a.txt.mkProgDir-new": "prev"},
+			expOut: `"prog/a.txt" already exists,` +
+				` the new file has been written to` +
+				` "prog/a.txt.mkProgDir-new.1"` + "\n",
+			expFiles: map[string]string{
+				"a.txt":                 "old",
+				"a.txt.mkProgDir-new":   "prev",
+				"a.txt.mkProgDir-new.1": "a",
+				"b/c.txt":               "c",
+				"new.txt":               "new",
+				"b/other.txt":           "other",
+			},
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"b/c.txt": &fstest.MapFile{Data: []byte("c")},
+		"new.txt": &fstest.MapFile{Data: []byte("new")},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		existing := map[string]string{
+			"prog/a.txt":       "old",
+			"prog/b/other.txt": "other",
+		}
+		maps.Copy(existing, tc.extraFiles)
+
+		for name, content := range existing {
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				t.Fatalf("cannot make the directory for %q: %s", name, err)
+			}
+
+			if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+				t.Fatalf("cannot write %q: %s", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.name = "prog"
+		prog.onConflict = tc.policy
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CreateTargetDir()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+
+		files := map[string]string{}
+
+		err := filepath.WalkDir("prog",
+			func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if d.IsDir() {
+					if d.Name() == journalDirName {
+						return filepath.SkipDir
+					}
+
+					return nil
+				}
+
+				b, err := os.ReadFile(path) //nolint:gosec
+				if err != nil {
+					return err
+				}
+
+				rel, _ := filepath.Rel("prog", path)
+				files[filepath.ToSlash(rel)] = string(b)
+
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("cannot read the program directory: %s", err)
+		}
+
+		err = testhelper.DiffVals(files, tc.expFiles)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: %s\n", err)
+		}
+	}
+}
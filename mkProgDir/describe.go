@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+type reportFormat string
+
+const (
+	rfTable = reportFormat("table")
+	rfJSON  = reportFormat("json")
+)
+
+// the kinds of template file
+const (
+	tfkFile     = "file"
+	tfkDir      = "directory"
+	tfkSymlink  = "symlink"
+	tfkCheck    = "check"
+	tfkRequires = "requires"
+	tfkHook     = "hook"
+)
+
+// templateFileDesc describes a template file and what it will produce
+type templateFileDesc struct {
+	Path        string   `json:"path"`
+	Target      string   `json:"target"`
+	Kind        string   `json:"kind"`
+	Generated   bool     `json:"generated"`
+	Optional    bool     `json:"optional"`
+	Permissions string   `json:"permissions"`
+	CheckTypes  []string `json:"checkTypes,omitempty"`
+	Severity    string   `json:"severity,omitempty"`
+	Macros      []string `json:"macros,omitempty"`
+}
+
+// kind returns the kind of template file
+func (tfi TemplateFileInfo) kind() string {
+	switch {
+	case tfi.isACheckFile:
+		return tfkCheck
+	case tfi.isARequiresFile:
+		return tfkRequires
+	case tfi.isAHook:
+		return tfkHook
+	case tfi.isADir:
+		return tfkDir
+	case tfi.isASymlink:
+		return tfkSymlink
+	}
+
+	return tfkFile
+}
+
+// describeFileFunc returns a function that will record the description of
+// each file in the template directory
+func (prog *Prog) describeFileFunc(descs *[]templateFileDesc) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		defer prog.stack.Start("describeFileFunc",
+			fmt.Sprintf("Start%25s: %q", "template file", path))()
+
+		intro := prog.stack.Tag()
+
+		if err != nil {
+			fmt.Fprintln(prog.out, err)
+			prog.SetExitStatus(esTemplate)
+
+			return nil
+		}
+
+		tfi, err := prog.getTemplateFileInfo(path, d)
+		if err != nil {
+			fmt.Fprintln(prog.out, err)
+			prog.SetExitStatus(esTemplate)
+
+			return nil
+		}
+
+		if tfi.isTheTemplateDir {
+			verboseSkipMsg(intro, "is the template dir")
+			return nil
+		}
+
+		desc := templateFileDesc{
+			Path:        prog.templateRelPath(path),
+			Target:      tfi.target,
+			Kind:        tfi.kind(),
+			Generated:   tfi.isAGenFile,
+			Optional:    tfi.isAnOptionalFile,
+			Permissions: fmt.Sprintf("%04o", tfi.perms),
+		}
+
+		if tfi.isACheckFile {
+			desc.CheckTypes = []string{tfi.checkTypeSuffix}
+			desc.Severity = string(tfi.severity)
+		}
+
+		if tfi.isAGenFile {
+			b, err := fs.ReadFile(prog.templateFS, path)
+			if err != nil {
+				fmt.Fprintf(prog.out,
+					"can't read the template file %q: %s\n", path, err)
+				prog.SetExitStatus(esIOError)
+
+				return nil
+			}
+
+			desc.Macros, _ = referencedMacros(prog.macroCache, string(b))
+		}
+
+		verbose.Printf("%s %30s: %s\n", intro, "kind", desc.Kind)
+
+		*descs = append(*descs, desc)
+
+		return nil
+	}
+}
+
+// templateRelPath returns the path relative to the template directory
+func (prog *Prog) templateRelPath(path string) string {
+	if prog.walkerBase == "." {
+		return path
+	}
+
+	return strings.TrimPrefix(path, prog.walkerBase+"/")
+}
+
+// addTargetCheckTypes adds the check types of the check files to the
+// descriptions of the files that they check
+func addTargetCheckTypes(descs []templateFileDesc) {
+	checkTypes := map[string][]string{}
+
+	for _, desc := range descs {
+		if desc.Kind == tfkCheck {
+			checkTypes[desc.Target] = append(checkTypes[desc.Target],
+				desc.CheckTypes...)
+		}
+	}
+
+	for i, desc := range descs {
+		if desc.Kind != tfkCheck {
+			descs[i].CheckTypes = checkTypes[desc.Target]
+		}
+	}
+}
+
+// yesOrBlank returns "yes" if the value is true and an empty string
+// otherwise
+func yesOrBlank(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return ""
+}
+
+// writeDescTable writes the template file descriptions as a table
+func (prog *Prog) writeDescTable(descs []templateFileDesc) error {
+	const padding = 2
+
+	tw := tabwriter.NewWriter(prog.out, 0, 0, padding, ' ', 0)
+
+	fmt.Fprintln(tw,
+		"Template file\tTarget\tKind\tGenerated\tOptional\tPerms"+
+			"\tCheck types\tMacros")
+
+	for _, desc := range descs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			desc.Path, desc.Target, desc.Kind,
+			yesOrBlank(desc.Generated), yesOrBlank(desc.Optional),
+			desc.Permissions,
+			strings.Join(desc.CheckTypes, ","),
+			strings.Join(desc.Macros, ","))
+	}
+
+	return tw.Flush()
+}
+
+// writeDescJSON writes the template file descriptions as JSON
+func (prog *Prog) writeDescJSON(descs []templateFileDesc) error {
+	enc := json.NewEncoder(prog.out)
+	enc.SetIndent("", "    ")
+
+	return enc.Encode(descs)
+}
+
+// DescribeTemplate reports the files in the template directory together
+// with what each will produce, the checks that will be applied to each
+// target and the macros that each generated file uses.
+func (prog *Prog) DescribeTemplate() {
+	defer prog.stack.Start("DescribeTemplate", "Start")()
+
+	descs := []templateFileDesc{}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		prog.describeFileFunc(&descs))
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Problem found walking the template directory: %s\n", err)
+		prog.SetExitStatus(esTemplate)
+	}
+
+	addTargetCheckTypes(descs)
+
+	switch prog.reportFormat {
+	case rfJSON:
+		err = prog.writeDescJSON(descs)
+	default:
+		err = prog.writeDescTable(descs)
+	}
+
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot write the description of the template: %s\n", err)
+		prog.SetExitStatus(esIOError)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestDescribeTemplate(t *testing.T) {
+	tmplFS := fstest.MapFS{
+		"main.go" + sfxGenerate: &fstest.MapFile{
+			Data: []byte("// ${" + macroProgName + "}\n"),
+		},
+		"main.go.begins" + sfxCheck: &fstest.MapFile{
+			Data: []byte("// "),
+		},
+		"doc.md" + sfxOptional: &fstest.MapFile{},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		format reportFormat
+		expOut string
+	}{
+		{
+			ID:     testhelper.MkID("table"),
+			format: rfTable,
+			expOut: "Template file" +
+				"                    Target   Kind   Generated  Optional" +
+				"  Perms  Check types  Macros\n" +
+				"doc.md--mkProgDir-Optional" +
+				"       doc.md   file              yes" +
+				"       0664                \n" +
+				"main.go--mkProgDir-Generate" +
+				"      main.go  file   yes" +
+				"                  0664   .begins      ProgName\n" +
+				"main.go.begins--mkProgDir-Check" +
+				"  main.go  check" +
+				"                       0664   .begins      \n",
+		},
+		{
+			ID:     testhelper.MkID("json"),
+			format: rfJSON,
+			expOut: `[
+    {
+        "path": "doc.md--mkProgDir-Optional",
+        "target": "doc.md",
+        "kind": "file",
+        "generated": false,
+        "optional": true,
+        "permissions": "0664"
+    },
+    {
+        "path": "main.go--mkProgDir-Generate",
+        "target": "main.go",
+        "kind": "file",
+        "generated": true,
+        "optional": false,
+        "permissions": "0664",
+        "checkTypes": [
+            ".begins"
+        ],
+        "macros": [
+            "ProgName"
+        ]
+    },
+    {
+        "path": "main.go.begins--mkProgDir-Check",
+        "target": "main.go",
+        "kind": "check",
+        "generated": false,
+        "optional": false,
+        "permissions": "0664",
+        "checkTypes": [
+            ".begins"
+        ],
+        "severity": "error"
+    }
+]
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.action = aDescTemplate
+		prog.reportFormat = tc.format
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.DescribeTemplate()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status", prog.exitStatus, 0)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The exit statuses of the program. Each category of failure has its own
+// exit status. If failures of more than one category are found the exit
+// status is that of the category with the highest priority, this is the
+// one with the highest value.
+const (
+	esOK       = 0
+	esFailure  = 1
+	esPerms    = 2
+	esContent  = 3
+	esMissing  = 4
+	esIOError  = 5
+	esTemplate = 6
+)
+
+// exitStatusDescs describes each of the exit statuses in increasing order
+// of priority
+var exitStatusDescs = []struct {
+	es   int
+	desc string
+}{
+	{esOK, "no problems were found"},
+	{esFailure, "some other failure, for instance a hook command failed," +
+		" a file already exists or there is nothing to undo"},
+	{esPerms, "a file or directory has unexpected permissions"},
+	{esContent, "a file has unexpected contents, a symbolic link points" +
+		" to the wrong place, the module or workspace files are" +
+		" incorrect, the program does not build or there are stray" +
+		" files"},
+	{esMissing, "a file, directory or symbolic link is missing or is" +
+		" of the wrong type"},
+	{esIOError, "a file or directory could not be read, written or" +
+		" created"},
+	{esTemplate, "there is a problem with the template"},
+}
+
+// exitStatusNotes returns a description of each of the exit statuses
+func exitStatusNotes() string {
+	var notes strings.Builder
+
+	for _, esd := range exitStatusDescs {
+		fmt.Fprintf(&notes, "- %d : %s\n", esd.es, esd.desc)
+	}
+
+	return notes.String()
+}
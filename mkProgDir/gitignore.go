@@ -0,0 +1,127 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+const gitignoreFileName = ".gitignore"
+
+// ignorePattern records a single pattern from a file in gitignore format
+type ignorePattern struct {
+	base     string // the directory of the file giving the pattern
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules holds the patterns from files in gitignore format. The
+// paths are slash-separated and relative to the root of the tree being
+// searched.
+type ignoreRules struct {
+	patterns []ignorePattern
+}
+
+// add parses the contents of a file in gitignore format found in the base
+// directory and adds the patterns to the rules. Only the common forms are
+// supported: blank lines and comments, leading '!' to negate a pattern,
+// trailing '/' to match only directories, a '/' at the start or in the
+// middle of a pattern to anchor it to the base directory and '**' to match
+// any number of directories.
+func (ir *ignoreRules) add(base, contents string) {
+	for line := range strings.SplitSeq(contents, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ip := ignorePattern{base: path.Clean(base)}
+
+		if strings.HasPrefix(line, "!") {
+			ip.negate = true
+			line = line[1:]
+		}
+
+		line = strings.TrimPrefix(line, `\`)
+
+		if strings.HasSuffix(line, "/") {
+			ip.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			ip.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		ip.pattern = line
+		ir.patterns = append(ir.patterns, ip)
+	}
+}
+
+// ignored returns true if the path is ignored by the rules. As with git,
+// the last matching pattern decides whether the path is ignored.
+func (ir ignoreRules) ignored(relPath string, isDir bool) bool {
+	relPath = path.Clean(relPath)
+	ignored := false
+
+	for _, ip := range ir.patterns {
+		if ip.dirOnly && !isDir {
+			continue
+		}
+
+		p := relPath
+		if ip.base != "." {
+			var found bool
+
+			p, found = strings.CutPrefix(relPath, ip.base+"/")
+			if !found {
+				continue
+			}
+		}
+
+		if !ip.anchored {
+			p = path.Base(p)
+		}
+
+		if globMatch(strings.Split(ip.pattern, "/"), strings.Split(p, "/")) {
+			ignored = !ip.negate
+		}
+	}
+
+	return ignored
+}
+
+// globMatch returns true if the path parts match the pattern parts. A
+// pattern part of "**" matches any number of path parts, any other pattern
+// part must match the corresponding path part (as for path.Match).
+func globMatch(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(parts) + 1 {
+				if globMatch(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+			return false
+		}
+
+		pattern, parts = pattern[1:], parts[1:]
+	}
+
+	return len(parts) == 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestIgnored(t *testing.T) {
+	var rules ignoreRules
+
+	rules.add(".", "# a comment\n"+
+		"\n"+
+		"*.log\n"+
+		"!keep.log\n"+
+		"build/\n"+
+		"/prog\n"+
+		"docs/**/*.tmp\n")
+	rules.add("sub", "local.txt\n")
+
+	testCases := []struct {
+		testhelper.ID
+		path   string
+		isDir  bool
+		expVal bool
+	}{
+		{
+			ID:     testhelper.MkID("matches anywhere"),
+			path:   "a/b/x.log",
+			expVal: true,
+		},
+		{
+			ID:   testhelper.MkID("negated"),
+			path: "a/keep.log",
+		},
+		{
+			ID:     testhelper.MkID("directory only - a directory"),
+			path:   "a/build",
+			isDir:  true,
+			expVal: true,
+		},
+		{
+			ID:   testhelper.MkID("directory only - a file"),
+			path: "a/build",
+		},
+		{
+			ID:     testhelper.MkID("anchored - at the top"),
+			path:   "prog",
+			expVal: true,
+		},
+		{
+			ID:   testhelper.MkID("anchored - not at the top"),
+			path: "a/prog",
+		},
+		{
+			ID:     testhelper.MkID("double star - no directories"),
+			path:   "docs/x.tmp",
+			expVal: true,
+		},
+		{
+			ID:     testhelper.MkID("double star - several directories"),
+			path:   "docs/a/b/x.tmp",
+			expVal: true,
+		},
+		{
+			ID:     testhelper.MkID("sub-directory pattern - in the directory"),
+			path:   "sub/c/local.txt",
+			expVal: true,
+		},
+		{
+			ID:   testhelper.MkID("sub-directory pattern - elsewhere"),
+			path: "other/local.txt",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "ignored",
+			rules.ignored(tc.path, tc.isDir), tc.expVal)
+	}
+}
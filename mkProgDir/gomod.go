@@ -0,0 +1,501 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const goModFileName = "go.mod"
+
+// modRequirement records the minimum version of a module that the
+// template requires
+type modRequirement struct {
+	modPath    string
+	minVersion string
+	source     string
+}
+
+// parseRequirements parses the contents of a requirements template file and
+// returns the module requirements it gives. Each non-blank line (after any
+// comment, starting with a '#', has been removed) must hold a module path
+// and a version, separated by white space.
+func parseRequirements(source, contents string) ([]modRequirement, error) {
+	reqs := []modRequirement{}
+
+	for i, line := range strings.Split(contents, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+
+		loc := source + ":" + strconv.Itoa(i+1)
+
+		if len(parts) != 2 { //nolint:mnd
+			return nil, fmt.Errorf(
+				"%s: a requirement should be a module path and a version,"+
+					" found %d parts", loc, len(parts))
+		}
+
+		modPath, version := parts[0], parts[1]
+
+		if err := module.CheckPath(modPath); err != nil {
+			return nil, fmt.Errorf("%s: bad module path: %w", loc, err)
+		}
+
+		if !semver.IsValid(version) {
+			return nil, fmt.Errorf("%s: bad version: %q", loc, version)
+		}
+
+		if err := module.Check(modPath, version); err != nil {
+			return nil, fmt.Errorf("%s: %w", loc, err)
+		}
+
+		reqs = append(reqs, modRequirement{
+			modPath:    modPath,
+			minVersion: version,
+			source:     source,
+		})
+	}
+
+	return reqs, nil
+}
+
+// addRequirements adds the requirements to those already recorded. If there
+// is already a requirement for a module the higher version is kept.
+func (prog *Prog) addRequirements(reqs []modRequirement) {
+	for _, r := range reqs {
+		idx := slices.IndexFunc(prog.requirements,
+			func(pr modRequirement) bool { return pr.modPath == r.modPath })
+		if idx < 0 {
+			prog.requirements = append(prog.requirements, r)
+			continue
+		}
+
+		if semver.Compare(r.minVersion, prog.requirements[idx].minVersion) > 0 {
+			prog.requirements[idx] = r
+		}
+	}
+}
+
+// addRequirementsFile parses the requirements from the template file and
+// records them
+func (prog *Prog) addRequirementsFile(tfi TemplateFileInfo) error {
+	reqs, err := parseRequirements(tfi.path, tfi.contents)
+	if err != nil {
+		return err
+	}
+
+	prog.addRequirements(reqs)
+
+	return nil
+}
+
+// findFileUpwards searches upwards from the directory for a regular file
+// with the given name and returns its full path. It returns an empty string
+// if no such file is found.
+func findFileUpwards(dir, fileName string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		name := filepath.Join(dir, fileName)
+
+		fi, err := os.Stat(name)
+		if err == nil && fi.Mode().IsRegular() {
+			return name, nil
+		}
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// findGoMod searches upwards from the directory for a go.mod file and
+// returns its name. It returns an empty string if no go.mod file is found.
+func findGoMod(dir string) (string, error) {
+	return findFileUpwards(dir, goModFileName)
+}
+
+// importedPackages returns the import paths of all the packages imported by
+// the Go files in the list.
+func importedPackages(goFiles []string) ([]string, error) {
+	imports := []string{}
+	fset := token.NewFileSet()
+
+	for _, fName := range goFiles {
+		f, err := parser.ParseFile(fset, fName, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imp := range f.Imports {
+			ip, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad import path %s: %w",
+					fName, imp.Path.Value, err)
+			}
+
+			if !slices.Contains(imports, ip) {
+				imports = append(imports, ip)
+			}
+		}
+	}
+
+	return imports, nil
+}
+
+// importsModule returns true if any of the import paths is in the module
+func importsModule(imports []string, modPath string) bool {
+	for _, ip := range imports {
+		if ip == modPath || strings.HasPrefix(ip, modPath+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiredVersion returns the version of the module required by the
+// modfile. It returns an empty string if the module is not required.
+func requiredVersion(mf *modfile.File, modPath string) string {
+	for _, r := range mf.Require {
+		if r.Mod.Path == modPath {
+			return r.Mod.Version
+		}
+	}
+
+	return ""
+}
+
+// generatedGoFiles returns those targets which are Go files and which
+// exist.
+func (prog *Prog) generatedGoFiles() []string {
+	goFiles := []string{}
+
+	for _, target := range prog.targets {
+		if filepath.Ext(target) != ".go" {
+			continue
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			continue
+		}
+
+		goFiles = append(goFiles, target)
+	}
+
+	return goFiles
+}
+
+// CheckRequirements checks that the go.mod file for the module containing
+// the target directory requires each module imported by the generated
+// files at, or above, the minimum version given in the template. If the
+// action is aFix then any outdated or missing requirements are updated.
+func (prog *Prog) CheckRequirements() {
+	if len(prog.requirements) == 0 {
+		return
+	}
+
+	// the module files may be shared with other program directories
+	// being checked at the same time
+	prog.modFilesMu.Lock()
+	defer prog.modFilesMu.Unlock()
+
+	defer prog.stack.Start("CheckRequirements", "Start")()
+
+	intro := prog.stack.Tag()
+
+	goModName, err := findGoMod(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot find the %s file for %q: %s\n",
+			goModFileName, prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if goModName == "" {
+		if prog.action == aFix {
+			prog.CreateGoMod()
+			return
+		}
+
+		fmt.Fprintf(prog.out,
+			"%q is not in a Go module (no %s file was found)\n",
+			prog.dir, goModFileName)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %q\n", intro, "module file", goModName)
+
+	imports, err := importedPackages(prog.generatedGoFiles())
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot find the imported packages: %s\n", err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	content, err := os.ReadFile(goModName) //nolint:gosec
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot read %q: %s\n", goModName, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	mf, err := modfile.Parse(goModName, content, nil)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot parse %q: %s\n", goModName, err)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	changed := false
+
+	for _, r := range prog.requirements {
+		if !importsModule(imports, r.modPath) {
+			prog.verboseSkip(intro, "module not imported: "+r.modPath)
+			continue
+		}
+
+		v := requiredVersion(mf, r.modPath)
+		if v != "" && semver.Compare(v, r.minVersion) >= 0 {
+			prog.verbosef("%s %30s: %s %s\n", intro, "requirement OK",
+				r.modPath, v)
+
+			continue
+		}
+
+		if prog.action == aFix {
+			if err := mf.AddRequire(r.modPath, r.minVersion); err != nil {
+				fmt.Fprintf(prog.out,
+					"Cannot update the requirement for %q: %s\n",
+					r.modPath, err)
+				prog.SetExitStatus(esFailure)
+
+				continue
+			}
+
+			fmt.Fprintf(prog.out,
+				"%q: the requirement for %q has been set to %s\n",
+				goModName, r.modPath, r.minVersion)
+
+			changed = true
+
+			continue
+		}
+
+		if v == "" {
+			fmt.Fprintf(prog.out,
+				"%q does not require module %q\n", goModName, r.modPath)
+		} else {
+			fmt.Fprintf(prog.out,
+				"%q requires an outdated version of module %q\n",
+				goModName, r.modPath)
+			fmt.Fprintf(prog.out, "\t   required version %s\n", v)
+		}
+
+		fmt.Fprintf(prog.out, "\t    minimum version %s\n", r.minVersion)
+		prog.SetExitStatus(esContent)
+	}
+
+	if changed {
+		prog.writeModFile(goModName, mf)
+	}
+}
+
+// writeModFile formats the modfile and writes it out to the named file.
+func (prog *Prog) writeModFile(name string, mf *modfile.File) {
+	mf.Cleanup()
+
+	content, err := mf.Format()
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot format %q: %s\n", name, err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot update %q: %s\n", name, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	before := prog.journalBefore(name)
+
+	err = writeFileAtomic(name, content, fi.Mode()&os.ModePerm)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot update %q: %s\n", name, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.journalAfter(name, before)
+
+	fmt.Fprintf(prog.out, "%q has been updated,"+
+		" you may need to run 'go mod tidy' to update the go.sum file\n",
+		name)
+}
+
+// goVersion returns the Go language version to be given in a generated
+// go.mod file. This is the version of the Go toolchain used to build this
+// program. If this is not a valid Go version (for instance, if it is a
+// development version) an empty string is returned.
+func goVersion() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	if !modfile.GoVersionRE.MatchString(v) {
+		return ""
+	}
+
+	return v
+}
+
+// goModulePath returns the module path to be used in a generated go.mod
+// file. This is the value of the module-path parameter if it has been
+// given, otherwise it is the program name.
+func (prog *Prog) goModulePath() string {
+	if prog.modulePath != "" {
+		return prog.modulePath
+	}
+
+	return prog.name
+}
+
+// makeGoMod returns a new modfile for the target directory. It requires
+// those modules in the template requirements which are imported by the
+// Go files in the target directory.
+func (prog *Prog) makeGoMod() (*modfile.File, error) {
+	imports, err := importedPackages(prog.generatedGoFiles())
+	if err != nil {
+		return nil, err
+	}
+
+	mf := new(modfile.File)
+
+	if err := mf.AddModuleStmt(prog.goModulePath()); err != nil {
+		return nil, err
+	}
+
+	if v := goVersion(); v != "" {
+		if err := mf.AddGoStmt(v); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range prog.requirements {
+		if importsModule(imports, r.modPath) {
+			mf.AddNewRequire(r.modPath, r.minVersion, false)
+		}
+	}
+
+	mf.Cleanup()
+
+	return mf, nil
+}
+
+// CreateGoMod creates a go.mod file in the target directory with the
+// module path given by the module-path parameter.
+func (prog *Prog) CreateGoMod() {
+	defer prog.stack.Start("CreateGoMod", "Start")()
+
+	intro := prog.stack.Tag()
+
+	name := filepath.Join(prog.dir, goModFileName)
+
+	mf, err := prog.makeGoMod()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot make the %s file: %s\n", goModFileName, err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	content, err := mf.Format()
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot format %q: %s\n", name, err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	before := prog.journalBefore(name)
+
+	err = writeFileAtomic(name, content, prog.filePerms&^prog.umask)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Can't create %q: %s\n", name, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.journalAfter(name, before)
+
+	prog.verbosef("%s %30s: %q\n", intro, "module file created", name)
+
+	if prog.action == aFix {
+		fmt.Fprintf(prog.out, "%q has been created\n", name)
+	}
+}
+
+// CreateGoModIfNeeded creates a go.mod file in the target directory if the
+// template has generated Go files and the target directory is not already
+// in a Go module.
+func (prog *Prog) CreateGoModIfNeeded() {
+	defer prog.stack.Start("CreateGoModIfNeeded", "Start")()
+
+	intro := prog.stack.Tag()
+
+	if len(prog.generatedGoFiles()) == 0 {
+		verboseSkipMsg(intro, "no Go files have been generated")
+		return
+	}
+
+	goModName, err := findGoMod(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot find the %s file for %q: %s\n",
+			goModFileName, prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if goModName != "" {
+		verboseSkipMsg(intro, "already in a module: "+goModName)
+		return
+	}
+
+	prog.CreateGoMod()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseRequirements(t *testing.T) {
+	const source = "test/go.mod--mkProgDir-Requires"
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		contents string
+		expReqs  []modRequirement
+	}{
+		{
+			ID:      testhelper.MkID("empty"),
+			expReqs: []modRequirement{},
+		},
+		{
+			ID: testhelper.MkID("good - with comments and blank lines"),
+			contents: "# a comment\n" +
+				"\n" +
+				"example.com/a v1.2.3 # trailing comment\n" +
+				"  example.com/b/v2   v2.0.1\n",
+			expReqs: []modRequirement{
+				{
+					modPath:    "example.com/a",
+					minVersion: "v1.2.3",
+					source:     source,
+				},
+				{
+					modPath:    "example.com/b/v2",
+					minVersion: "v2.0.1",
+					source:     source,
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("bad - missing version"),
+			ExpErr: testhelper.MkExpErr(
+				source + ":1: a requirement should be"),
+			contents: "example.com/a\n",
+		},
+		{
+			ID: testhelper.MkID("bad - bad version"),
+			ExpErr: testhelper.MkExpErr(
+				source + ":2: bad version: \"1.2.3\""),
+			contents: "\nexample.com/a 1.2.3\n",
+		},
+		{
+			ID:       testhelper.MkID("bad - major version mismatch"),
+			ExpErr:   testhelper.MkExpErr(source + ":1: "),
+			contents: "example.com/a/v2 v1.2.3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		reqs, err := parseRequirements(source, tc.contents)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			err = testhelper.DiffVals(reqs, tc.expReqs)
+			if err != nil {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: %s\n", err)
+			}
+		}
+	}
+}
+
+func TestImportsModule(t *testing.T) {
+	imports := []string{
+		"os",
+		"example.com/a/pkg",
+		"example.com/b",
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		modPath string
+		expVal  bool
+	}{
+		{
+			ID:      testhelper.MkID("package in module"),
+			modPath: "example.com/a",
+			expVal:  true,
+		},
+		{
+			ID:      testhelper.MkID("module root package"),
+			modPath: "example.com/b",
+			expVal:  true,
+		},
+		{
+			ID:      testhelper.MkID("module path prefix but not module"),
+			modPath: "example.com/b/v2",
+			expVal:  false,
+		},
+		{
+			ID:      testhelper.MkID("partial path element"),
+			modPath: "example.com/aa",
+			expVal:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "importsModule",
+			importsModule(imports, tc.modPath), tc.expVal)
+	}
+}
+
+func TestGoModulePath(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		modulePath string
+		expPath    string
+	}{
+		{
+			ID:      testhelper.MkID("default"),
+			expPath: "prog",
+		},
+		{
+			ID:         testhelper.MkID("given"),
+			modulePath: "example.com/prog",
+			expPath:    "example.com/prog",
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := NewProg()
+		prog.name = "prog"
+		prog.modulePath = tc.modulePath
+
+		testhelper.DiffString(t, tc.IDStr(), "module path",
+			prog.goModulePath(), tc.expPath)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	goWorkFileName = "go.work"
+	goWorkEnvVar   = "GOWORK"
+)
+
+// findGoWork returns the name of the go.work file for the directory. As
+// with the go command, the GOWORK environment variable is used if it is
+// set and if it is set to "off" then no go.work file is used. Otherwise it
+// searches upwards from the directory. It returns an empty string if no
+// go.work file is found.
+func findGoWork(dir string) (string, error) {
+	if gw := os.Getenv(goWorkEnvVar); gw != "" {
+		if gw == "off" {
+			return "", nil
+		}
+
+		return gw, nil
+	}
+
+	return findFileUpwards(dir, goWorkFileName)
+}
+
+// workUsePath returns the path of the directory as it should appear in a
+// use directive in the go.work file. The directory path is given relative
+// to the directory containing the go.work file, using '/' as a separator
+// and starting with "./" so the use directive is not mistaken for a module
+// path.
+func workUsePath(goWorkName, dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(goWorkName), absDir)
+	if err != nil {
+		return "", err
+	}
+
+	rel = filepath.ToSlash(rel)
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		return rel, nil
+	}
+
+	return "./" + rel, nil
+}
+
+// workUses returns true if the go.work file has a use directive for the
+// directory.
+func workUses(goWorkName string, wf *modfile.WorkFile, dir string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	workDir := filepath.Dir(goWorkName)
+
+	for _, u := range wf.Use {
+		usePath := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(usePath) {
+			usePath = filepath.Join(workDir, usePath)
+		}
+
+		if filepath.Clean(usePath) == absDir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readGoWork reads and parses the go.work file
+func readGoWork(goWorkName string) (*modfile.WorkFile, error) {
+	content, err := os.ReadFile(goWorkName) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return modfile.ParseWork(goWorkName, content, nil)
+}
+
+// hasOwnGoMod returns true if the target directory has a go.mod file
+func (prog *Prog) hasOwnGoMod() bool {
+	fi, err := os.Stat(filepath.Join(prog.dir, goModFileName))
+
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// addToGoWork adds a use directive for the target directory to the go.work
+// file, sorting the use directives, and writes the file out.
+func (prog *Prog) addToGoWork(goWorkName string, wf *modfile.WorkFile) {
+	usePath, err := workUsePath(goWorkName, prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot add %q to %q: %s\n", prog.dir, goWorkName, err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	if err := wf.AddUse(usePath, ""); err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot add %q to %q: %s\n", prog.dir, goWorkName, err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	wf.SortBlocks()
+	wf.Cleanup()
+
+	fi, err := os.Stat(goWorkName)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot update %q: %s\n", goWorkName, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	before := prog.journalBefore(goWorkName)
+
+	err = writeFileAtomic(goWorkName, modfile.Format(wf.Syntax),
+		fi.Mode()&os.ModePerm)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot update %q: %s\n", goWorkName, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.journalAfter(goWorkName, before)
+
+	fmt.Fprintf(prog.out, "%q has been added to %q\n", usePath, goWorkName)
+}
+
+// AddToWorkspace finds the go.work file for the target directory and adds
+// the target directory to it. This is only done if the target directory
+// has its own go.mod file.
+func (prog *Prog) AddToWorkspace() {
+	// the module files may be shared with other program directories
+	// being checked at the same time
+	prog.modFilesMu.Lock()
+	defer prog.modFilesMu.Unlock()
+
+	defer prog.stack.Start("AddToWorkspace", "Start")()
+
+	intro := prog.stack.Tag()
+
+	if !prog.hasOwnGoMod() {
+		prog.verboseSkip(intro, "no "+goModFileName+" in the target directory")
+		return
+	}
+
+	goWorkName, err := findGoWork(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot find the %s file for %q: %s\n",
+			goWorkFileName, prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if goWorkName == "" {
+		fmt.Fprintf(prog.out,
+			"Cannot add %q to the workspace: no %s file was found\n",
+			prog.dir, goWorkFileName)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	wf, err := readGoWork(goWorkName)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot read %q: %s\n", goWorkName, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if workUses(goWorkName, wf, prog.dir) {
+		prog.verboseSkip(intro, "already in "+goWorkName)
+		return
+	}
+
+	prog.addToGoWork(goWorkName, wf)
+}
+
+// CheckWorkspace checks that, if the target directory has its own go.mod
+// file and there is a go.work file for it, the target directory is used in
+// the go.work file. If the action is aFix then the target directory is
+// added to the go.work file if it is missing.
+func (prog *Prog) CheckWorkspace() {
+	// the module files may be shared with other program directories
+	// being checked at the same time
+	prog.modFilesMu.Lock()
+	defer prog.modFilesMu.Unlock()
+
+	defer prog.stack.Start("CheckWorkspace", "Start")()
+
+	intro := prog.stack.Tag()
+
+	if !prog.hasOwnGoMod() {
+		prog.verboseSkip(intro, "no "+goModFileName+" in the target directory")
+		return
+	}
+
+	goWorkName, err := findGoWork(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot find the %s file for %q: %s\n",
+			goWorkFileName, prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if goWorkName == "" {
+		prog.verboseSkip(intro, "not in a workspace")
+		return
+	}
+
+	wf, err := readGoWork(goWorkName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			prog.verboseSkip(intro, "no workspace file: "+goWorkName)
+			return
+		}
+
+		fmt.Fprintf(prog.out, "Cannot read %q: %s\n", goWorkName, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if workUses(goWorkName, wf, prog.dir) {
+		prog.verbosef("%s %30s: %q\n", intro, "in workspace", goWorkName)
+		return
+	}
+
+	if prog.action == aFix {
+		prog.addToGoWork(goWorkName, wf)
+		return
+	}
+
+	fmt.Fprintf(prog.out, "%q is not used in the workspace file %q\n",
+		prog.dir, goWorkName)
+	prog.SetExitStatus(esContent)
+}
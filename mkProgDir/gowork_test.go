@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+	"golang.org/x/mod/modfile"
+)
+
+func TestWorkUsePath(t *testing.T) {
+	goWorkName := filepath.FromSlash("/ws/go.work")
+
+	testCases := []struct {
+		testhelper.ID
+		dir    string
+		expVal string
+	}{
+		{
+			ID:     testhelper.MkID("sub-directory"),
+			dir:    filepath.FromSlash("/ws/cmd/prog"),
+			expVal: "./cmd/prog",
+		},
+		{
+			ID:     testhelper.MkID("workspace directory"),
+			dir:    filepath.FromSlash("/ws"),
+			expVal: ".",
+		},
+		{
+			ID:     testhelper.MkID("outside the workspace directory"),
+			dir:    filepath.FromSlash("/other/prog"),
+			expVal: "../other/prog",
+		},
+	}
+
+	for _, tc := range testCases {
+		usePath, err := workUsePath(goWorkName, tc.dir)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\tunexpected error: %s\n", err)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "use path", usePath, tc.expVal)
+	}
+}
+
+func TestWorkUses(t *testing.T) {
+	goWorkName := filepath.FromSlash("/ws/go.work")
+
+	wf, err := modfile.ParseWork(goWorkName,
+		[]byte("go 1.26\n\nuse (\n\t./a\n\tb/c/\n)\n"), nil)
+	if err != nil {
+		t.Fatalf("cannot parse the go.work file: %s", err)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		dir    string
+		expVal bool
+	}{
+		{
+			ID:     testhelper.MkID("used"),
+			dir:    filepath.FromSlash("/ws/a"),
+			expVal: true,
+		},
+		{
+			ID:     testhelper.MkID("used - unclean use path"),
+			dir:    filepath.FromSlash("/ws/b/c"),
+			expVal: true,
+		},
+		{
+			ID:  testhelper.MkID("not used"),
+			dir: filepath.FromSlash("/ws/d"),
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "workUses",
+			workUses(goWorkName, wf, tc.dir), tc.expVal)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type hookEvent string
+
+const (
+	hookPostCreate = hookEvent("post-create")
+	hookPostFix    = hookEvent("post-fix")
+)
+
+var hookEvents = []hookEvent{hookPostCreate, hookPostFix}
+
+// hookCmd records a command to be run after the files have been created or
+// fixed
+type hookCmd struct {
+	source string
+	id     int
+	args   []string
+}
+
+// String returns the command as a single string. Any argument which is
+// empty or which contains white space or quotes is shown quoted.
+func (hc hookCmd) String() string {
+	args := make([]string, 0, len(hc.args))
+
+	for _, a := range hc.args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\") {
+			a = strconv.Quote(a)
+		}
+
+		args = append(args, a)
+	}
+
+	return strings.Join(args, " ")
+}
+
+// parseHookName splits the name of the hook file into the event and the
+// numeric id (zero if not present). It returns an error if the event is
+// not recognised.
+func parseHookName(name string) (hookEvent, int, error) {
+	base := trimNumSuffix(name)
+
+	id := 0
+	if base != name {
+		var err error
+
+		id, err = strconv.Atoi(strings.TrimPrefix(name, base+"."))
+		if err != nil {
+			return "", 0, fmt.Errorf("bad hook id in %q: %w", name, err)
+		}
+	}
+
+	event := hookEvent(base)
+	if !slices.Contains(hookEvents, event) {
+		return "", 0, fmt.Errorf(
+			"unknown hook event %q, it should be one of %q", base, hookEvents)
+	}
+
+	return event, id, nil
+}
+
+// splitHookLine splits the line into words in the manner of a shell. Words
+// are separated by white space. Characters between single quotes are taken
+// literally. Between double quotes a backslash escapes a following double
+// quote or backslash, otherwise it is taken literally. Elsewhere a
+// backslash escapes the following character. A '#' at the start of a word
+// starts a comment which runs to the end of the line. It returns an error
+// if a quote is not closed or the line ends with a backslash.
+func splitHookLine(line string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+			continue
+		case r == '#' && !inWord:
+			return words, nil
+		}
+
+		inWord = true
+
+		switch r {
+		case '\\':
+			i++
+			if i == len(runes) {
+				return nil, errors.New("the line ends with a backslash")
+			}
+
+			word.WriteRune(runes[i])
+		case '\'':
+			end := slices.Index(runes[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("a single quote is not closed")
+			}
+
+			word.WriteString(string(runes[i+1 : i+1+end]))
+			i += end + 1
+		case '"':
+			closed := false
+
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+
+				if runes[i] == '\\' && i+1 < len(runes) &&
+					(runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+
+				word.WriteRune(runes[i])
+			}
+
+			if !closed {
+				return nil, errors.New("a double quote is not closed")
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// parseHookCmds parses the contents of a hook file and returns the commands
+// it gives. Each non-blank line (after any comment has been removed) is a
+// command and its arguments. The line is split into words as described for
+// splitHookLine so that arguments containing white space can be given in
+// quotes. It returns an error giving the line number if any line cannot be
+// split.
+func parseHookCmds(source string, id int, contents string) ([]hookCmd, error) {
+	cmds := []hookCmd{}
+
+	lineNum := 0
+
+	for line := range strings.SplitSeq(contents, "\n") {
+		lineNum++
+
+		args, err := splitHookLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		cmds = append(cmds, hookCmd{source: source, id: id, args: args})
+	}
+
+	return cmds, nil
+}
+
+// addHook records the hook commands in the template file. The commands are
+// kept in order of their numeric id.
+func (prog *Prog) addHook(tfi TemplateFileInfo) error {
+	event, id, err := parseHookName(filepath.Base(tfi.target))
+	if err != nil {
+		return fmt.Errorf("%q : %w", tfi.path, err)
+	}
+
+	cmds, err := parseHookCmds(tfi.path, id, tfi.contents)
+	if err != nil {
+		return fmt.Errorf("%q : %w", tfi.path, err)
+	}
+
+	prog.hooks[event] = append(prog.hooks[event], cmds...)
+
+	slices.SortStableFunc(prog.hooks[event],
+		func(a, b hookCmd) int { return cmp.Compare(a.id, b.id) })
+
+	return nil
+}
+
+// RunHooks runs the commands for the given event in the target directory.
+// The output of each command is reported. If any command fails the
+// remaining commands are not run and the exit status is set. Any changes
+// the commands make to the target directory are recorded in the journal.
+func (prog *Prog) RunHooks(event hookEvent) {
+	defer prog.stack.Start("RunHooks", string(event))()
+
+	intro := prog.stack.Tag()
+
+	if !prog.runHooks {
+		prog.verboseSkip(intro, "hooks are not to be run")
+		return
+	}
+
+	if len(prog.hooks[event]) == 0 {
+		return
+	}
+
+	defer prog.journalChangesSince(prog.targetDirStates())
+
+	for _, hc := range prog.hooks[event] {
+		prog.verbosef("%s %30s: %s\n", intro, "hook source", hc.source)
+
+		fmt.Fprintf(prog.out, "Running %s hook: %s\n", event, hc)
+
+		cmd := exec.Command(hc.args[0], hc.args[1:]...) //nolint:gosec
+		cmd.Dir = prog.dir
+
+		out, err := cmd.CombinedOutput()
+		for line := range strings.Lines(string(out)) {
+			fmt.Fprintf(prog.out, "\t%s\n", strings.TrimRight(line, "\n"))
+		}
+
+		if err != nil {
+			fmt.Fprintf(prog.out,
+				"The %s hook failed: %q: %s\n", event, hc, err)
+			prog.SetExitStatus(esFailure)
+
+			return
+		}
+	}
+}
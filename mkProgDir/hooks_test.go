@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseHookName(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		name     string
+		expEvent hookEvent
+		expID    int
+	}{
+		{
+			ID:       testhelper.MkID("post-create, no id"),
+			name:     "post-create",
+			expEvent: hookPostCreate,
+		},
+		{
+			ID:       testhelper.MkID("post-fix, with id"),
+			name:     "post-fix.12",
+			expEvent: hookPostFix,
+			expID:    12,
+		},
+		{
+			ID:     testhelper.MkID("bad event"),
+			ExpErr: testhelper.MkExpErr(`unknown hook event "pre-create"`),
+			name:   "pre-create.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		event, id, err := parseHookName(tc.name)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "event", event, tc.expEvent)
+			testhelper.DiffInt(t, tc.IDStr(), "id", id, tc.expID)
+		}
+	}
+}
+
+func TestParseHookCmds(t *testing.T) {
+	const (
+		source = "post-create" + sfxHook
+		id     = 3
+	)
+
+	cmds, err := parseHookCmds(source, id,
+		"# a comment\n"+
+			"\n"+
+			"go mod tidy\n"+
+			"  gofmt   -l . # list badly formatted files\n"+
+			`git commit -m "a \"quoted\" message" -m 'it''s' x#y\ z`+"\n")
+	if err != nil {
+		t.Fatalf("parseHookCmds: unexpected error: %s", err)
+	}
+
+	expCmds := []hookCmd{
+		{source: source, id: id, args: []string{"go", "mod", "tidy"}},
+		{source: source, id: id, args: []string{"gofmt", "-l", "."}},
+		{
+			source: source, id: id,
+			args: []string{
+				"git", "commit",
+				"-m", `a "quoted" message`,
+				"-m", "its",
+				"x#y z",
+			},
+		},
+	}
+
+	err = testhelper.DiffVals(cmds, expCmds)
+	if err != nil {
+		t.Log("parseHookCmds")
+		t.Errorf("\t: %s\n", err)
+	}
+}
+
+func TestSplitHookLine(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		line     string
+		expWords []string
+	}{
+		{
+			ID:   testhelper.MkID("blank"),
+			line: "   ",
+		},
+		{
+			ID:       testhelper.MkID("single quotes"),
+			line:     `echo 'a "b" \c'`,
+			expWords: []string{"echo", `a "b" \c`},
+		},
+		{
+			ID:       testhelper.MkID("double quotes"),
+			line:     `echo "a 'b' \c \\ \""`,
+			expWords: []string{"echo", `a 'b' \c \ "`},
+		},
+		{
+			ID:       testhelper.MkID("empty argument"),
+			line:     `echo "" x`,
+			expWords: []string{"echo", "", "x"},
+		},
+		{
+			ID:     testhelper.MkID("unclosed single quote"),
+			ExpErr: testhelper.MkExpErr("a single quote is not closed"),
+			line:   "echo 'abc",
+		},
+		{
+			ID:     testhelper.MkID("unclosed double quote"),
+			ExpErr: testhelper.MkExpErr("a double quote is not closed"),
+			line:   `echo "abc`,
+		},
+		{
+			ID:     testhelper.MkID("trailing backslash"),
+			ExpErr: testhelper.MkExpErr("the line ends with a backslash"),
+			line:   `echo abc\`,
+		},
+	}
+
+	for _, tc := range testCases {
+		words, err := splitHookLine(tc.line)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffStringSlice(t, tc.IDStr(), "words",
+				words, tc.expWords)
+		}
+	}
+}
+
+func TestPostFixHookGate(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		contents   string
+		expHookRan bool
+	}{
+		{
+			ID:         testhelper.MkID("fix succeeded"),
+			contents:   "abc",
+			expHookRan: true,
+		},
+		{
+			ID:       testhelper.MkID("fix failed"),
+			contents: "xyz",
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("abc")},
+		"a.txt" + containsSuffix + sfxCheck: &fstest.MapFile{
+			Data: []byte("abc"),
+		},
+		string(hookPostFix) + sfxHook: &fstest.MapFile{
+			Data: []byte("touch hook-ran\n"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		if err := os.Mkdir("prog", 0o755); err != nil {
+			t.Fatalf("cannot make the program directory: %s", err)
+		}
+
+		err := os.WriteFile(filepath.Join("prog", "a.txt"),
+			[]byte(tc.contents), 0o600)
+		if err != nil {
+			t.Fatalf("cannot write the target file: %s", err)
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.name = "prog"
+		prog.action = aFix
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CheckTargetDir()
+
+		_, err = os.Stat(filepath.Join("prog", "hook-ran"))
+		testhelper.DiffBool(t, tc.IDStr(), "hook ran",
+			err == nil, tc.expHookRan)
+	}
+}
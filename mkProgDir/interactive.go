@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/location.mod/location"
+	"golang.org/x/term"
+)
+
+// templateMacro records a macro referenced by the template and the
+// template files which use it
+type templateMacro struct {
+	name   string
+	usedBy []string
+}
+
+// desc returns a description of the macro
+func (tm templateMacro) desc() string {
+	for _, mi := range availableMacros {
+		if mi.name == tm.name {
+			return mi.desc
+		}
+	}
+
+	return "used in " + english.Join(tm.usedBy, ", ", " and ")
+}
+
+// isAvailable returns true if the macro is one of the available macros,
+// these are always given a value by the program
+func (tm templateMacro) isAvailable() bool {
+	return slices.ContainsFunc(availableMacros,
+		func(mi macroInfo) bool { return mi.name == tm.name })
+}
+
+// templateMacros returns the macros referenced by the generated files in
+// the template in order of first use
+func (prog *Prog) templateMacros() ([]templateMacro, error) {
+	tms := []templateMacro{}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || !strings.HasSuffix(path, sfxGenerate) {
+				return nil
+			}
+
+			b, err := fs.ReadFile(prog.templateFS, path)
+			if err != nil {
+				return err
+			}
+
+			names, err := referencedMacros(prog.macroCache, string(b))
+			if err != nil {
+				return fmt.Errorf("%q : %w", path, err)
+			}
+
+			for _, name := range names {
+				i := slices.IndexFunc(tms,
+					func(tm templateMacro) bool { return tm.name == name })
+				if i < 0 {
+					tms = append(tms, templateMacro{name: name})
+					i = len(tms) - 1
+				}
+
+				tms[i].usedBy = append(tms[i].usedBy,
+					prog.templateRelPath(path))
+			}
+
+			return nil
+		})
+
+	return tms, err
+}
+
+// macroValue returns the value of the macro and true if it has a value,
+// otherwise it returns false.
+func (prog *Prog) macroValue(name string) (string, bool) {
+	val, err := prog.macroCache.Find(name, location.New("macro value"))
+
+	return val, err == nil
+}
+
+// missingMacros returns the names of those macros which have no value
+func (prog *Prog) missingMacros(tms []templateMacro) []string {
+	missing := []string{}
+
+	for _, tm := range tms {
+		if _, ok := prog.macroValue(tm.name); !ok {
+			missing = append(missing, tm.name)
+		}
+	}
+
+	return missing
+}
+
+// readLine reads a line from the reader and returns it without the
+// trailing newline. An error is returned if nothing can be read.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptForMacros prompts for a value for each of the macros referenced by
+// the template, showing the description of the macro and its current
+// value, if any, as the default. The available macros are not prompted
+// for as their values are set by the program.
+func (prog *Prog) promptForMacros(r *bufio.Reader, tms []templateMacro,
+) error {
+	for _, tm := range tms {
+		if tm.isAvailable() {
+			continue
+		}
+
+		dflt, hasDflt := prog.macroValue(tm.name)
+
+		fmt.Fprintf(prog.out, "%s: %s\n", tm.name, tm.desc())
+
+		for {
+			if hasDflt {
+				fmt.Fprintf(prog.out, "\tvalue [%s]: ", dflt)
+			} else {
+				fmt.Fprint(prog.out, "\tvalue: ")
+			}
+
+			val, err := readLine(r)
+			if err != nil {
+				return fmt.Errorf("cannot read the value of %q: %w",
+					tm.name, err)
+			}
+
+			if val == "" {
+				if !hasDflt {
+					continue
+				}
+
+				val = dflt
+			}
+
+			prog.macroCache.AddMacro(tm.name, val)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// targetsToCreate returns the targets that will be created from the
+// template
+func (prog *Prog) targetsToCreate() ([]string, error) {
+	targets := []string{}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			tfi, err := prog.getTemplateFileInfo(path, d)
+			if err != nil {
+				return err
+			}
+
+			if tfi.isTheTemplateDir ||
+				tfi.isACheckFile ||
+				tfi.isARequiresFile ||
+				tfi.isAHook {
+				return nil
+			}
+
+			targets = append(targets, tfi.target)
+
+			return nil
+		})
+
+	return targets, err
+}
+
+// confirmCreate shows the targets that will be created and asks for
+// confirmation. It returns true if the user confirms.
+func (prog *Prog) confirmCreate(r *bufio.Reader) (bool, error) {
+	targets, err := prog.targetsToCreate()
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(prog.out, "The following will be created in %q:\n", prog.dir)
+
+	for _, t := range targets {
+		fmt.Fprintf(prog.out, "\t%s\n", t)
+	}
+
+	fmt.Fprint(prog.out, "Create them? [y/N]: ")
+
+	reply, err := readLine(r)
+	if err != nil {
+		return false, err
+	}
+
+	reply = strings.ToLower(strings.TrimSpace(reply))
+
+	return reply == "y" || reply == "yes", nil
+}
+
+// inIsATerminal returns true if the input is a terminal
+func (prog *Prog) inIsATerminal() bool {
+	f, ok := prog.in.(*os.File)
+
+	return ok && term.IsTerminal(int(f.Fd())) //nolint:gosec
+}
+
+// CreateInteractively prompts for values for the macros referenced by the
+// template, confirms the targets to be created and then creates them. If
+// the input is not a terminal it reports an error, listing any macros that
+// have no value.
+func (prog *Prog) CreateInteractively() {
+	defer prog.stack.Start("CreateInteractively", "Start")()
+
+	tms, err := prog.templateMacros()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot find the macros used by the template: %s\n", err)
+		prog.SetExitStatus(esTemplate)
+
+		return
+	}
+
+	if !prog.inIsATerminal() {
+		fmt.Fprintln(prog.out,
+			"Cannot create interactively: the input is not a terminal")
+
+		if missing := prog.missingMacros(tms); len(missing) > 0 {
+			fmt.Fprintf(prog.out,
+				"The template uses these macros which have no value: %s\n",
+				english.Join(missing, ", ", " and "))
+			fmt.Fprintf(prog.out,
+				"Give them values with the '%s' parameter (name%svalue)\n",
+				paramNameMacro, macroDefSep)
+		}
+
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	r := bufio.NewReader(prog.in)
+
+	if err := prog.promptForMacros(r, tms); err != nil {
+		fmt.Fprintln(prog.out, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	ok, err := prog.confirmCreate(r)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot confirm the files to create: %s\n", err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if !ok {
+		fmt.Fprintln(prog.out, "Nothing has been created")
+		return
+	}
+
+	prog.CreateTargetDir()
+}
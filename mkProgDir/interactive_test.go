@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// interactiveTestFS is the template used by the interactive tests
+var interactiveTestFS = fstest.MapFS{
+	"doc.txt" + sfxGenerate: &fstest.MapFile{
+		Data: []byte("${" + macroProgName + "} ${Author} ${Year}\n"),
+	},
+	"README" + sfxGenerate: &fstest.MapFile{
+		Data: []byte("${Author}\n"),
+	},
+}
+
+func TestPromptForMacros(t *testing.T) {
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.out = &out
+	prog.templateFS = interactiveTestFS
+	prog.walkerBase = "."
+	prog.name = "prog"
+	prog.macroDefs = []string{"Year=2026"}
+	prog.addAllMacros()
+
+	tms, err := prog.templateMacros()
+	if err != nil {
+		t.Fatalf("unexpected error finding the template macros: %s", err)
+	}
+
+	r := bufio.NewReader(strings.NewReader("\nAnn\n\n"))
+
+	err = prog.promptForMacros(r, tms)
+	if err != nil {
+		t.Fatalf("unexpected error prompting for the macros: %s", err)
+	}
+
+	testhelper.DiffString(t, "promptForMacros", "output", out.String(),
+		"Author: used in README--mkProgDir-Generate"+
+			" and doc.txt--mkProgDir-Generate\n"+
+			"\tvalue: \tvalue: "+
+			"Year: used in doc.txt--mkProgDir-Generate\n"+
+			"\tvalue [2026]: ")
+
+	for name, expVal := range map[string]string{
+		"Author": "Ann",
+		"Year":   "2026",
+	} {
+		val, _ := prog.macroValue(name)
+		testhelper.DiffString(t, "promptForMacros", name, val, expVal)
+	}
+}
+
+func TestCreateInteractivelyNotATerminal(t *testing.T) {
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.in = strings.NewReader("")
+	prog.out = &out
+	prog.templateFS = interactiveTestFS
+	prog.walkerBase = "."
+	prog.name = "prog"
+	prog.addAllMacros()
+
+	prog.CreateInteractively()
+
+	testhelper.DiffString(t, "not a terminal", "output", out.String(),
+		"Cannot create interactively: the input is not a terminal\n"+
+			"The template uses these macros which have no value:"+
+			" Author and Year\n"+
+			"Give them values with the 'macro' parameter (name=value)\n")
+	testhelper.DiffInt(t, "not a terminal", "exit status",
+		prog.exitStatus, esFailure)
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const (
+	journalDirName  = ".mkProgDir-undo"
+	journalFileName = "journal.json"
+)
+
+// the kinds of filesystem object recorded in the journal
+const (
+	fsKindFile    = "file"
+	fsKindDir     = "directory"
+	fsKindSymlink = "symlink"
+)
+
+// fileState records the state of a file, directory or symbolic link. For a
+// file which has been saved so that it can be restored, it records the
+// name of the saved copy.
+type fileState struct {
+	Kind       string      `json:"kind"`
+	Hash       string      `json:"hash,omitempty"`
+	LinkTarget string      `json:"linkTarget,omitempty"`
+	Perms      fs.FileMode `json:"perms,omitempty"`
+	Saved      string      `json:"saved,omitempty"`
+}
+
+// same returns true if the two states have the same kind and contents
+func (fst fileState) same(other fileState) bool {
+	return fst.Kind == other.Kind &&
+		fst.Hash == other.Hash &&
+		fst.LinkTarget == other.LinkTarget
+}
+
+// the operations recorded in the journal
+const (
+	jopMade    = "made"
+	jopChanged = "changed"
+	jopMoved   = "moved"
+)
+
+// journalEntry records a single change made to the filesystem. The path
+// is relative to the target directory if possible. A moved entry records
+// that the path was moved to MovedTo.
+type journalEntry struct {
+	Op      string     `json:"op"`
+	Path    string     `json:"path"`
+	MovedTo string     `json:"movedTo,omitempty"`
+	Before  *fileState `json:"before,omitempty"`
+	After   *fileState `json:"after,omitempty"`
+}
+
+// journal records the changes made to the filesystem by a run of the
+// program so that they can be undone. It is safe for concurrent use.
+type journal struct {
+	mu        sync.Mutex
+	started   bool
+	savedFile int
+	Entries   []journalEntry `json:"entries"`
+}
+
+// getFileState returns the state of the file, directory or symbolic link
+func getFileState(path string) (fileState, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	fst := fileState{Perms: fi.Mode().Perm()}
+
+	switch {
+	case fi.IsDir():
+		fst.Kind = fsKindDir
+	case fi.Mode()&fs.ModeSymlink != 0:
+		fst.Kind = fsKindSymlink
+
+		fst.LinkTarget, err = os.Readlink(path)
+		if err != nil {
+			return fileState{}, err
+		}
+	default:
+		fst.Kind = fsKindFile
+
+		b, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return fileState{}, err
+		}
+
+		sum := sha256.Sum256(b)
+		fst.Hash = hex.EncodeToString(sum[:])
+	}
+
+	return fst, nil
+}
+
+// journalDir returns the name of the directory holding the journal
+func (prog *Prog) journalDir() string {
+	return filepath.Join(prog.dir, journalDirName)
+}
+
+// journalPath returns the path to be recorded in the journal, this is
+// relative to the target directory if possible
+func (prog *Prog) journalPath(path string) string {
+	absDir, errDir := filepath.Abs(prog.dir)
+	absPath, errPath := filepath.Abs(path)
+
+	if errDir != nil || errPath != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return absPath
+	}
+
+	return rel
+}
+
+// fromJournalPath returns the path from the journal as a usable path
+func (prog *Prog) fromJournalPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(prog.dir, path)
+}
+
+// startJournal removes any journal from a previous run. This is done when
+// the first change of this run is recorded so that a run which makes no
+// changes leaves the previous journal in place.
+func (prog *Prog) startJournal() {
+	if prog.journal.started {
+		return
+	}
+
+	prog.journal.started = true
+
+	_ = os.RemoveAll(prog.journalDir())
+}
+
+// addJournalEntry records the entry in the journal
+func (prog *Prog) addJournalEntry(je journalEntry) {
+	prog.journal.Entries = append(prog.journal.Entries, je)
+}
+
+// journalBefore records the state of the path before it is changed. If
+// the path exists then a copy is saved so that it can be restored. It
+// returns nil if the path does not exist.
+func (prog *Prog) journalBefore(path string) *fileState {
+	prog.journal.mu.Lock()
+	defer prog.journal.mu.Unlock()
+
+	fst, err := getFileState(path)
+	if err != nil {
+		return nil
+	}
+
+	prog.startJournal()
+
+	if fst.Kind != fsKindFile {
+		return &fst
+	}
+
+	prog.journal.savedFile++
+	fst.Saved = "saved." + strconv.Itoa(prog.journal.savedFile)
+
+	savedName := filepath.Join(prog.journalDir(), fst.Saved)
+
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err == nil {
+		err = os.MkdirAll(prog.journalDir(), prog.dirPerms)
+	}
+
+	if err == nil {
+		err = writeFileAtomic(savedName, b, fst.Perms)
+	}
+
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot save a copy of %q, the change cannot be undone: %s\n",
+			path, err)
+
+		fst.Saved = ""
+	}
+
+	return &fst
+}
+
+// journalAfter records the change to the path. If there was no state
+// before then the path has been made otherwise it has been changed.
+func (prog *Prog) journalAfter(path string, before *fileState) {
+	prog.journal.mu.Lock()
+	defer prog.journal.mu.Unlock()
+
+	after, err := getFileState(path)
+	if err != nil {
+		return
+	}
+
+	prog.startJournal()
+
+	je := journalEntry{
+		Op:    jopMade,
+		Path:  prog.journalPath(path),
+		After: &after,
+	}
+
+	if before != nil {
+		je.Op = jopChanged
+		je.Before = before
+	}
+
+	prog.addJournalEntry(je)
+}
+
+// journalMoved records that the path has been moved
+func (prog *Prog) journalMoved(path, movedTo string) {
+	prog.journal.mu.Lock()
+	defer prog.journal.mu.Unlock()
+
+	after, err := getFileState(movedTo)
+	if err != nil {
+		return
+	}
+
+	prog.startJournal()
+
+	prog.addJournalEntry(journalEntry{
+		Op:      jopMoved,
+		Path:    prog.journalPath(path),
+		MovedTo: prog.journalPath(movedTo),
+		After:   &after,
+	})
+}
+
+// targetDirStates returns the state of everything in the target
+// directory, apart from the journal, keyed by the path recorded in the
+// journal
+func (prog *Prog) targetDirStates() map[string]fileState {
+	states := map[string]fileState{}
+
+	_ = filepath.WalkDir(prog.dir,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			if d.IsDir() && d.Name() == journalDirName {
+				return fs.SkipDir
+			}
+
+			if fst, err := getFileState(path); err == nil {
+				states[prog.journalPath(path)] = fst
+			}
+
+			return nil
+		})
+
+	return states
+}
+
+// journalChangesSince records any changes made in the target directory
+// since the states were taken by something other than this program (such
+// as a hook command). New entries are recorded as having been made and the
+// recorded state of any entries already made or changed is updated. Any
+// other changed entry is recorded as changed but with no saved copy, so
+// the run cannot then be undone.
+func (prog *Prog) journalChangesSince(states map[string]fileState) {
+	newStates := prog.targetDirStates()
+
+	prog.journal.mu.Lock()
+	defer prog.journal.mu.Unlock()
+
+	known := map[string]int{}
+
+	for i, je := range prog.journal.Entries {
+		if je.Op == jopMoved {
+			known[je.MovedTo] = i
+			continue
+		}
+
+		known[je.Path] = i
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(newStates)) {
+		fst := newStates[path]
+
+		if i, ok := known[path]; ok {
+			prog.journal.Entries[i].After = &fst
+			continue
+		}
+
+		before, existed := states[path]
+		if existed && before.same(fst) {
+			continue
+		}
+
+		prog.startJournal()
+
+		je := journalEntry{Op: jopMade, Path: path, After: &fst}
+		if existed {
+			je.Op = jopChanged
+			je.Before = &before
+		}
+
+		prog.addJournalEntry(je)
+	}
+}
+
+// writeJournal writes the journal, if any changes have been recorded, into
+// the target directory
+func (prog *Prog) writeJournal() {
+	prog.journal.mu.Lock()
+	defer prog.journal.mu.Unlock()
+
+	if len(prog.journal.Entries) == 0 {
+		return
+	}
+
+	b, err := json.MarshalIndent(prog.journal, "", "    ")
+	if err == nil {
+		err = os.MkdirAll(prog.journalDir(), prog.dirPerms)
+	}
+
+	if err == nil {
+		err = writeFileAtomic(
+			filepath.Join(prog.journalDir(), journalFileName),
+			b, prog.filePerms&^prog.umask)
+	}
+
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot write the journal, the changes cannot be undone: %s\n",
+			err)
+		prog.SetExitStatus(esIOError)
+	}
+}
+
+// readJournal reads the journal from the target directory
+func (prog *Prog) readJournal() (*journal, error) {
+	b, err := os.ReadFile( //nolint:gosec
+		filepath.Join(prog.journalDir(), journalFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	j := &journal{}
+
+	err = json.Unmarshal(b, j)
+
+	return j, err
+}
+
+// undoProblems returns a description of each of the changes made since the
+// journal was written which would prevent it from being undone.
+func (prog *Prog) undoProblems(j *journal) []string {
+	problems := []string{}
+	known := map[string]bool{journalDirName: true}
+
+	for _, je := range j.Entries {
+		known[je.Path] = true
+		known[je.MovedTo] = true
+	}
+
+	for _, je := range j.Entries {
+		path := prog.fromJournalPath(je.Path)
+		if je.Op == jopMoved {
+			path = prog.fromJournalPath(je.MovedTo)
+		}
+
+		fst, err := getFileState(path)
+		if err != nil {
+			problems = append(problems,
+				fmt.Sprintf("%q cannot be checked: %s", path, err))
+
+			continue
+		}
+
+		if je.After != nil && !fst.same(*je.After) {
+			problems = append(problems,
+				fmt.Sprintf("%q has been changed", path))
+
+			continue
+		}
+
+		if je.Op == jopChanged && je.Before != nil &&
+			je.Before.Kind == fsKindFile && je.Before.Saved == "" {
+			problems = append(problems,
+				fmt.Sprintf("%q has no saved copy to restore", path))
+		}
+
+		if je.Op != jopMade || fst.Kind != fsKindDir {
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			problems = append(problems,
+				fmt.Sprintf("%q cannot be read: %s", path, err))
+
+			continue
+		}
+
+		for _, e := range entries {
+			ePath := filepath.Join(je.Path, e.Name())
+			if !known[ePath] {
+				problems = append(problems,
+					fmt.Sprintf("%q has been added",
+						prog.fromJournalPath(ePath)))
+			}
+		}
+	}
+
+	return problems
+}
+
+// restore restores the path to the state recorded before it was changed
+func (prog *Prog) restore(path string, before *fileState) error {
+	switch before.Kind {
+	case fsKindSymlink:
+		return symlinkAtomic(before.LinkTarget, path)
+	case fsKindFile:
+		b, err := os.ReadFile( //nolint:gosec
+			filepath.Join(prog.journalDir(), before.Saved))
+		if err != nil {
+			return err
+		}
+
+		if fi, err := os.Lstat(path); err == nil && fi.IsDir() {
+			return fmt.Errorf("%q is a directory", path)
+		}
+
+		return writeFileAtomic(path, b, before.Perms)
+	}
+
+	return fmt.Errorf("a %s cannot be restored", before.Kind)
+}
+
+// undoEntry reverses the change recorded in the journal entry
+func (prog *Prog) undoEntry(je journalEntry) error {
+	path := prog.fromJournalPath(je.Path)
+
+	switch je.Op {
+	case jopMade:
+		if je.After != nil && je.After.Kind == fsKindDir &&
+			filepath.Clean(path) == filepath.Clean(prog.dir) {
+			if err := os.RemoveAll(prog.journalDir()); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(prog.out, "%q has been removed\n", path)
+	case jopChanged:
+		if err := prog.restore(path, je.Before); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(prog.out, "%q has been restored\n", path)
+	case jopMoved:
+		movedTo := prog.fromJournalPath(je.MovedTo)
+		if err := os.Rename(movedTo, path); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(prog.out, "%q has been moved back to %q\n", movedTo, path)
+	default:
+		return fmt.Errorf("unknown journal operation: %q", je.Op)
+	}
+
+	return nil
+}
+
+// Undo reverses the changes made by the most recent run which changed the
+// target directory. It will not undo anything if any of the files have
+// been changed since.
+func (prog *Prog) Undo() {
+	defer prog.stack.Start("Undo", "Start")()
+
+	j, err := prog.readJournal()
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(prog.out, "There is nothing to undo in %q\n", prog.dir)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot read the journal in %q: %s\n",
+			prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if problems := prog.undoProblems(j); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(prog.out, p)
+		}
+
+		fmt.Fprintln(prog.out,
+			"Nothing has been undone as files have changed since the"+
+				" last run")
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	for _, je := range slices.Backward(j.Entries) {
+		if err := prog.undoEntry(je); err != nil {
+			fmt.Fprintf(prog.out, "Cannot undo the change to %q: %s\n",
+				prog.fromJournalPath(je.Path), err)
+			prog.SetExitStatus(esIOError)
+
+			return
+		}
+	}
+
+	if err := os.RemoveAll(prog.journalDir()); err != nil {
+		fmt.Fprintf(prog.out, "Cannot remove the journal in %q: %s\n",
+			prog.dir, err)
+		prog.SetExitStatus(esIOError)
+	}
+}
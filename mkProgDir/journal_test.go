@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// progFiles returns the contents of the files in the directory, keyed by
+// their path relative to the directory. The journal is not included. A
+// nil map is returned if the directory does not exist.
+func progFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	files := map[string]string{}
+
+	err := filepath.WalkDir(dir,
+		func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if d.Name() == journalDirName {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			b, err := os.ReadFile(path) //nolint:gosec
+			if err != nil {
+				return err
+			}
+
+			rel, _ := filepath.Rel(dir, path)
+			files[filepath.ToSlash(rel)] = string(b)
+
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("cannot read the directory %q: %s", dir, err)
+	}
+
+	return files
+}
+
+func TestUndo(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		existing      map[string]string
+		policy        conflictPolicy
+		change        func() error
+		expOut        string
+		expExitStatus int
+		expFiles      map[string]string
+	}{
+		{
+			ID: testhelper.MkID("new dir"),
+			expOut: `"prog/new.txt" has been removed` + "\n" +
+				`"prog/b/c.txt" has been removed` + "\n" +
+				`"prog/b" has been removed` + "\n" +
+				`"prog/a.txt" has been removed` + "\n" +
+				`"prog" has been removed` + "\n",
+		},
+		{
+			ID: testhelper.MkID("new dir, file changed"),
+			change: func() error {
+				return os.WriteFile("prog/a.txt", []byte("changed"), 0o600)
+			},
+			expOut: `"prog/a.txt" has been changed` + "\n" +
+				"Nothing has been undone as files have changed" +
+				" since the last run\n",
+			expExitStatus: esFailure,
+			expFiles: map[string]string{
+				"a.txt":   "changed",
+				"b/c.txt": "c",
+				"new.txt": "new",
+			},
+		},
+		{
+			ID: testhelper.MkID("new dir, file added"),
+			change: func() error {
+				return os.WriteFile("prog/b/d.txt", []byte("d"), 0o600)
+			},
+			expOut: `"prog/b/d.txt" has been added` + "\n" +
+				"Nothing has been undone as files have changed" +
+				" since the last run\n",
+			expExitStatus: esFailure,
+			expFiles: map[string]string{
+				"a.txt":   "a",
+				"b/c.txt": "c",
+				"b/d.txt": "d",
+				"new.txt": "new",
+			},
+		},
+		{
+			ID:       testhelper.MkID("existing dir, overwrite"),
+			existing: map[string]string{"a.txt": "old", "x.txt": "x"},
+			policy:   ocOverwrite,
+			expOut: `"prog/new.txt" has been removed` + "\n" +
+				`"prog/b/c.txt" has been removed` + "\n" +
+				`"prog/b" has been removed` + "\n" +
+				`"prog/a.txt" has been restored` + "\n",
+			expFiles: map[string]string{"a.txt": "old", "x.txt": "x"},
+		},
+		{
+			ID:       testhelper.MkID("existing dir, backup"),
+			existing: map[string]string{"a.txt": "old"},
+			policy:   ocBackup,
+			expOut: `"prog/new.txt" has been removed` + "\n" +
+				`"prog/b/c.txt" has been removed` + "\n" +
+				`"prog/b" has been removed` + "\n" +
+				`"prog/a.txt" has been removed` + "\n" +
+				`"prog/a.txt.mkProgDir-backup" has been moved back` +
+				` to "prog/a.txt"` + "\n",
+			expFiles: map[string]string{"a.txt": "old"},
+		},
+		{
+			ID:       testhelper.MkID("existing dir, skip, nothing made"),
+			existing: map[string]string{"a.txt": "old", "b/c.txt": "old c"},
+			policy:   ocSkip,
+			change: func() error {
+				return os.Remove("prog/new.txt")
+			},
+			expOut: `"prog/new.txt" cannot be checked: ` +
+				"lstat prog/new.txt: no such file or directory\n" +
+				"Nothing has been undone as files have changed" +
+				" since the last run\n",
+			expExitStatus: esFailure,
+			expFiles: map[string]string{
+				"a.txt":   "old",
+				"b/c.txt": "old c",
+			},
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"b/c.txt": &fstest.MapFile{Data: []byte("c")},
+		"new.txt": &fstest.MapFile{Data: []byte("new")},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		for name, content := range tc.existing {
+			name = filepath.Join("prog", name)
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				t.Fatalf("cannot make the directory for %q: %s", name, err)
+			}
+
+			if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+				t.Fatalf("cannot write %q: %s", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.name = "prog"
+		prog.onConflict = tc.policy
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CreateTargetDir()
+
+		if prog.exitStatus != 0 {
+			t.Fatalf("%s: cannot create the program directory:\n%s",
+				tc.IDStr(), out.String())
+		}
+
+		if tc.change != nil {
+			if err := tc.change(); err != nil {
+				t.Fatalf("%s: cannot change the program directory: %s",
+					tc.IDStr(), err)
+			}
+		}
+
+		out.Reset()
+
+		prog = NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+
+		prog.Undo()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+
+		err := testhelper.DiffVals(progFiles(t, "prog"), tc.expFiles)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: %s\n", err)
+		}
+	}
+}
+
+func TestUndoNothingToUndo(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := os.Mkdir("prog", 0o755); err != nil {
+		t.Fatalf("cannot make the program directory: %s", err)
+	}
+
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.out = &out
+	prog.dir = "prog"
+
+	prog.Undo()
+
+	testhelper.DiffString(t, "nothing to undo", "output", out.String(),
+		`There is nothing to undo in "prog"`+"\n")
+	testhelper.DiffInt(t, "nothing to undo", "exit status",
+		prog.exitStatus, esFailure)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// sfxBase is the start of all the template file suffixes
+const sfxBase = "--mkProgDir-"
+
+// lintProblem records a problem found in a template file
+type lintProblem struct {
+	path string
+	msg  string
+}
+
+// templateLint records the state of the linting of a template directory
+type templateLint struct {
+	problems []lintProblem
+	targets  map[string][]TemplateFileInfo
+	checks   map[string][]TemplateFileInfo
+	noMacro  map[string]bool
+}
+
+// addProblem records a problem with the template file
+func (tl *templateLint) addProblem(path, format string, args ...any) {
+	tl.problems = append(tl.problems,
+		lintProblem{path: path, msg: fmt.Sprintf(format, args...)})
+}
+
+// lintMacros checks that every macro referenced by a generated file is
+// defined. Undefined macros are reported and then given an empty value so
+// that the rest of the template can be checked. It returns false if the
+// file cannot be read or a macro is not properly terminated.
+func (prog *Prog) lintMacros(tl *templateLint, path string) bool {
+	b, err := fs.ReadFile(prog.templateFS, path)
+	if err != nil {
+		tl.addProblem(path, "can't read the template file: %s", err)
+		return false
+	}
+
+	names, err := referencedMacros(prog.macroCache, string(b))
+	if err != nil {
+		tl.addProblem(path, "%s", err)
+		return false
+	}
+
+	loc := location.New(path)
+
+	for _, name := range names {
+		if !tl.noMacro[name] {
+			if _, err := prog.macroCache.Find(name, loc); err == nil {
+				continue
+			}
+
+			tl.noMacro[name] = true
+			prog.macroCache.AddMacro(name, "")
+		}
+
+		tl.addProblem(path, "the macro %q is not defined", name)
+	}
+
+	return true
+}
+
+// lintCheckFile checks that the check file will generate a valid check
+func (tl *templateLint) lintCheckFile(tfi TemplateFileInfo) {
+	tl.checks[tfi.target] = append(tl.checks[tfi.target], tfi)
+
+	if tfi.checkTypeSuffix != matchesSuffix &&
+		tfi.checkTypeSuffix != doesNotMatchSuffix {
+		return
+	}
+
+	if _, err := regexp.Compile(tfi.contents); err != nil {
+		tl.addProblem(tfi.path, "bad regular expression: %s", err)
+	}
+}
+
+// lintFileFunc returns a function that will check each file in the
+// template directory, recording any problems found. The walk is never
+// aborted so that all the problems can be reported at once.
+func (prog *Prog) lintFileFunc(tl *templateLint) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		defer prog.stack.Start("lintFileFunc",
+			fmt.Sprintf("Start%25s: %q", "template file", path))()
+
+		intro := prog.stack.Tag()
+
+		if err != nil {
+			tl.addProblem(path, "%s", err)
+			return nil
+		}
+
+		if strings.HasSuffix(path, sfxGenerate) &&
+			!prog.lintMacros(tl, path) {
+			verboseSkipMsg(intro, "has bad macros")
+			return nil
+		}
+
+		tfi, err := prog.getTemplateFileInfo(path, d)
+		if err != nil {
+			tl.addProblem(path, "%s", err)
+			return nil
+		}
+
+		if tfi.isTheTemplateDir {
+			verboseSkipMsg(intro, "is the template dir")
+			return nil
+		}
+
+		if strings.Contains(filepath.Base(tfi.target), sfxBase) {
+			tl.addProblem(path, "unknown or misplaced suffix: %q",
+				filepath.Base(tfi.target))
+		}
+
+		switch {
+		case tfi.isACheckFile:
+			verbose.Printf("%s %30s: %s\n", intro, "", "a check file")
+			tl.lintCheckFile(tfi)
+		case tfi.isARequiresFile:
+			verbose.Printf("%s %30s: %s\n",
+				intro, "", "a requirements file")
+
+			_, err := parseRequirements(path, tfi.contents)
+			if err != nil {
+				tl.addProblem(path, "%s", err)
+			}
+		case tfi.isAHook:
+			verbose.Printf("%s %30s: %s\n", intro, "", "a hook file")
+
+			_, _, err := parseHookName(filepath.Base(tfi.target))
+			if err != nil {
+				tl.addProblem(path, "%s", err)
+			}
+		default:
+			tl.targets[tfi.target] = append(tl.targets[tfi.target], tfi)
+		}
+
+		return nil
+	}
+}
+
+// lintTargets checks for duplicate targets and for checks of files that
+// are not in the template
+func (tl *templateLint) lintTargets() {
+	for target, tfis := range tl.targets {
+		for _, tfi := range tfis[1:] {
+			tl.addProblem(tfi.path,
+				"the target (%q) is also produced by %q",
+				target, tfis[0].path)
+		}
+	}
+
+	for target, checks := range tl.checks {
+		tfis, ok := tl.targets[target]
+
+		for _, tfi := range checks {
+			switch {
+			case !ok:
+				tl.addProblem(tfi.path,
+					"the checked file (%q) is not in the template", target)
+			case tfis[0].isADir:
+				tl.addProblem(tfi.path,
+					"the checked file (%q) is a directory", target)
+			case tfis[0].isASymlink:
+				tl.addProblem(tfi.path,
+					"the checked file (%q) is a symbolic link", target)
+			}
+		}
+	}
+}
+
+// LintTemplate checks the template directory for problems and reports
+// them all, together with the template file in which each was found.
+func (prog *Prog) LintTemplate() {
+	defer prog.stack.Start("LintTemplate", "Start")()
+
+	intro := prog.stack.Tag()
+
+	tl := &templateLint{
+		targets: map[string][]TemplateFileInfo{},
+		checks:  map[string][]TemplateFileInfo{},
+		noMacro: map[string]bool{},
+	}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		prog.lintFileFunc(tl))
+	if err != nil {
+		tl.addProblem(prog.walkerBase, "%s", err)
+	}
+
+	tl.lintTargets()
+
+	verbose.Printf("%s %30s: %d\n",
+		intro, "problems found", len(tl.problems))
+
+	slices.SortStableFunc(tl.problems, func(a, b lintProblem) int {
+		return cmp.Compare(a.path, b.path)
+	})
+
+	for _, p := range tl.problems {
+		fmt.Fprintf(prog.out, "%q : %s\n", p.path, p.msg)
+	}
+
+	if len(tl.problems) > 0 {
+		prog.SetExitStatus(esTemplate)
+	}
+}
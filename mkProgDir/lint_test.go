@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestLintTemplate(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		tmplFS        fstest.MapFS
+		expOut        string
+		expExitStatus int
+	}{
+		{
+			ID: testhelper.MkID("good template"),
+			tmplFS: fstest.MapFS{
+				"main.go" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("// ${" + macroProgName + "}\n"),
+				},
+				"main.go.matches" + sfxCheck: &fstest.MapFile{
+					Data: []byte("^// "),
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("bad template"),
+			tmplFS: fstest.MapFS{
+				"a.go" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("${Undefined}\n"),
+				},
+				"a.go.matches" + sfxCheck: &fstest.MapFile{
+					Data: []byte("("),
+				},
+				"a.go": &fstest.MapFile{},
+				"b.go.begins" + sfxCheck: &fstest.MapFile{
+					Data: []byte("package"),
+				},
+				"c.go--mkProgDir-Optinal": &fstest.MapFile{},
+			},
+			expOut: `"a.go--mkProgDir-Generate" :` +
+				` the macro "Undefined" is not defined` + "\n" +
+				`"a.go--mkProgDir-Generate" :` +
+				` the target ("a.go") is also produced by "a.go"` + "\n" +
+				`"a.go.matches--mkProgDir-Check" :` +
+				" bad regular expression: error parsing regexp:" +
+				" missing closing ): `(`\n" +
+				`"b.go.begins--mkProgDir-Check" :` +
+				` the checked file ("b.go") is not in the template` + "\n" +
+				`"c.go--mkProgDir-Optinal" :` +
+				` unknown or misplaced suffix: "c.go--mkProgDir-Optinal"` +
+				"\n",
+			expExitStatus: esTemplate,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.action = aLintTemplate
+		prog.templateFS = tc.tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.LintTemplate()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
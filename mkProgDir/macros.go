@@ -1,5 +1,14 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/macros.mod/macros"
+)
+
 const (
 	macroProgName = "ProgName"
 )
@@ -17,7 +26,77 @@ var availableMacros = []macroInfo{
 	},
 }
 
+// macroDefSep separates the macro name from its value in a macro
+// definition
+const macroDefSep = "="
+
+// parseMacroDef splits the macro definition into the macro name and value.
+// It returns an error if the definition is badly formed or would redefine
+// one of the available macros.
+func parseMacroDef(def string) (string, string, error) {
+	name, val, ok := strings.Cut(def, macroDefSep)
+	if !ok {
+		return "", "", fmt.Errorf(
+			"the macro definition %q is not of the form name%svalue",
+			def, macroDefSep)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", errors.New("the macro name must not be empty")
+	}
+
+	for _, mi := range availableMacros {
+		if mi.name == name {
+			return "", "", fmt.Errorf("the macro %q cannot be redefined", name)
+		}
+	}
+
+	return name, val, nil
+}
+
+// checkMacroDef returns an error if the macro definition is not valid
+func checkMacroDef(def string) error {
+	_, _, err := parseMacroDef(def)
+	return err
+}
+
 // addAllMacros populates the macroCache
 func (prog *Prog) addAllMacros() {
 	prog.macroCache.AddMacro(macroProgName, prog.name)
+
+	for _, def := range prog.macroDefs {
+		name, val, err := parseMacroDef(def)
+		if err != nil { // can't happen - the definitions have been checked
+			panic(err)
+		}
+
+		prog.macroCache.AddMacro(name, val)
+	}
+}
+
+// referencedMacros returns the names of the macros referenced in the
+// contents in the order in which they first appear. It returns an error if
+// a macro is started but not finished.
+func referencedMacros(mc *macros.Cache, contents string) ([]string, error) {
+	start, end := mc.GetStartEndStrings()
+	names := []string{}
+
+	_, macroEtc, macroFound := strings.Cut(contents, start)
+	for macroFound {
+		name, remainder, macroTerminated := strings.Cut(macroEtc, end)
+		if !macroTerminated {
+			return names, fmt.Errorf(
+				"a macro was started with %q but not finished with %q",
+				start, end)
+		}
+
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+
+		_, macroEtc, macroFound = strings.Cut(remainder, start)
+	}
+
+	return names, nil
 }
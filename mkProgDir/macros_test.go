@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReferencedMacros(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		contents string
+		expNames []string
+	}{
+		{
+			ID:       testhelper.MkID("no macros"),
+			contents: "package main\n",
+			expNames: []string{},
+		},
+		{
+			ID:       testhelper.MkID("repeated macros"),
+			contents: "${A} ${B}\n${A}",
+			expNames: []string{"A", "B"},
+		},
+		{
+			ID: testhelper.MkID("unterminated macro"),
+			ExpErr: testhelper.MkExpErr(
+				`a macro was started with "${" but not finished with "}"`),
+			contents: "${A} ${B",
+		},
+	}
+
+	prog := NewProg()
+
+	for _, tc := range testCases {
+		names, err := referencedMacros(prog.macroCache, tc.contents)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffStringSlice(t, tc.IDStr(), "macro names",
+				names, tc.expNames)
+		}
+	}
+}
+
+func TestParseMacroDef(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		def     string
+		expName string
+		expVal  string
+	}{
+		{
+			ID:      testhelper.MkID("good"),
+			def:     "Author = A. N. Other=x",
+			expName: "Author",
+			expVal:  " A. N. Other=x",
+		},
+		{
+			ID:      testhelper.MkID("empty value"),
+			def:     "Author=",
+			expName: "Author",
+		},
+		{
+			ID:     testhelper.MkID("no separator"),
+			ExpErr: testhelper.MkExpErr("is not of the form name=value"),
+			def:    "Author",
+		},
+		{
+			ID:     testhelper.MkID("no name"),
+			ExpErr: testhelper.MkExpErr("the macro name must not be empty"),
+			def:    " =x",
+		},
+		{
+			ID: testhelper.MkID("redefinition"),
+			ExpErr: testhelper.MkExpErr(
+				`the macro "` + macroProgName + `" cannot be redefined`),
+			def: macroProgName + "=x",
+		},
+	}
+
+	for _, tc := range testCases {
+		name, val, err := parseMacroDef(tc.def)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "name", name, tc.expName)
+			testhelper.DiffString(t, tc.IDStr(), "value", val, tc.expVal)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// isAWordChar returns true if the byte can be part of a longer word
+// containing the program name
+func isAWordChar(b byte) bool {
+	return b == '_' || b == '-' ||
+		(b >= 'a' && b <= 'z') ||
+		(b >= 'A' && b <= 'Z') ||
+		(b >= '0' && b <= '9')
+}
+
+// replaceWord replaces each occurrence of the word in s with the
+// replacement. An occurrence only counts if it is not part of a longer
+// word. It returns the new string and the number of replacements made.
+func replaceWord(s, word, repl string) (string, int) {
+	var sb strings.Builder
+
+	count := 0
+
+	for {
+		i := strings.Index(s, word)
+		if i < 0 {
+			break
+		}
+
+		end := i + len(word)
+
+		if (i > 0 && isAWordChar(s[i-1])) ||
+			(end < len(s) && isAWordChar(s[end])) {
+			sb.WriteString(s[:end])
+			s = s[end:]
+
+			continue
+		}
+
+		sb.WriteString(s[:i])
+		sb.WriteString(repl)
+		s = s[end:]
+		count++
+	}
+
+	sb.WriteString(s)
+
+	return sb.String(), count
+}
+
+// firstLines returns the first n lines of the contents including the
+// trailing newline
+func firstLines(contents string, n int) string {
+	end := 0
+
+	for range n {
+		i := strings.IndexByte(contents[end:], '\n')
+		if i < 0 {
+			return contents
+		}
+
+		end += i + 1
+	}
+
+	return contents[:end]
+}
+
+// makeTemplateFile copies the file into the new template directory. If the
+// file contains the program name it is replaced with the program name macro
+// and the template file is made a generated file. If begins checks are
+// wanted and this is a Go file then a check file is also written.
+func (prog *Prog) makeTemplateFile(src, dest string, fi fs.FileInfo) error {
+	defer prog.stack.Start("makeTemplateFile",
+		fmt.Sprintf("Start%25s: %q", "file", src))()
+
+	intro := prog.stack.Tag()
+
+	b, err := os.ReadFile(src) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	contents := string(b)
+	startMacro, endMacro := prog.macroCache.GetStartEndStrings()
+	genSuffix := ""
+
+	if strings.Contains(contents, startMacro) {
+		fmt.Fprintf(prog.out,
+			"%q already contains %q, it is copied without any"+
+				" replacement of the program name\n", src, startMacro)
+	} else {
+		var count int
+
+		contents, count = replaceWord(contents, prog.name,
+			startMacro+macroProgName+endMacro)
+		if count > 0 {
+			verbose.Printf("%s %30s: %d\n", intro, "names replaced", count)
+
+			genSuffix = sfxGenerate
+		}
+	}
+
+	err = os.WriteFile(dest+genSuffix, []byte(contents), fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if prog.beginsCheckLines == 0 || filepath.Ext(src) != ".go" {
+		return nil
+	}
+
+	begins := firstLines(contents, prog.beginsCheckLines)
+
+	checkSuffix := ""
+	if genSuffix != "" && strings.Contains(begins, startMacro) {
+		checkSuffix = sfxGenerate
+	}
+
+	verbose.Printf("%s %30s: %s\n", intro, "", "adding a begins check")
+
+	return os.WriteFile(dest+beginsSuffix+sfxCheck+checkSuffix,
+		[]byte(begins), prog.filePerms)
+}
+
+// makeTemplateFunc returns a function that will copy the files in the
+// program directory into the new template directory. Files and
+// directories matched by the patterns in any .gitignore files are skipped
+// as are any .git directories, the undo journal and any per-project
+// configuration files.
+func (prog *Prog) makeTemplateFunc(rules *ignoreRules) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		defer prog.stack.Start("makeTemplateFunc",
+			fmt.Sprintf("Start%25s: %q", "file", path))()
+
+		intro := prog.stack.Tag()
+
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(prog.dir, path)
+		if err != nil {
+			return err
+		}
+
+		slashPath := filepath.ToSlash(relPath)
+		if relPath != "." &&
+			(d.Name() == ".git" || d.Name() == journalDirName ||
+				d.Name() == projectConfigFileName ||
+				rules.ignored(slashPath, d.IsDir())) {
+			verboseSkipMsg(intro, "ignored")
+
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		dest := filepath.Join(prog.newTemplateDir, relPath)
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(dest, fi.Mode().Perm()); err != nil {
+				return err
+			}
+
+			ignoreFile := filepath.Join(path, gitignoreFileName)
+
+			b, err := os.ReadFile(ignoreFile) //nolint:gosec
+			if err == nil {
+				rules.add(slashPath, string(b))
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(linkTarget, dest)
+		case d.Type().IsRegular():
+			return prog.makeTemplateFile(path, dest, fi)
+		default:
+			fmt.Fprintf(prog.out,
+				"%q is not a regular file, directory or symbolic link,"+
+					" it is not copied\n", path)
+		}
+
+		return nil
+	}
+}
+
+// MakeTemplate makes a new template directory from the program directory.
+func (prog *Prog) MakeTemplate() {
+	defer prog.stack.Start("MakeTemplate", "Start")()
+
+	err := filepath.WalkDir(prog.dir,
+		prog.makeTemplateFunc(&ignoreRules{}))
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot make the template %q from %q: %s\n",
+			prog.newTemplateDir, prog.dir, err)
+		prog.SetExitStatus(esIOError)
+	}
+}
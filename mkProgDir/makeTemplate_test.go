@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReplaceWord(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		s        string
+		expVal   string
+		expCount int
+	}{
+		{
+			ID:     testhelper.MkID("no occurrences"),
+			s:      "package main\n",
+			expVal: "package main\n",
+		},
+		{
+			ID:       testhelper.MkID("whole words only"),
+			s:        "prog progs prog-x x_prog (prog) prog.go",
+			expVal:   "X progs prog-x x_prog (X) X.go",
+			expCount: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		val, count := replaceWord(tc.s, "prog", "X")
+		testhelper.DiffString(t, tc.IDStr(), "value", val, tc.expVal)
+		testhelper.DiffInt(t, tc.IDStr(), "count", count, tc.expCount)
+	}
+}
+
+func TestFirstLines(t *testing.T) {
+	const contents = "a\nb\nc"
+
+	testCases := []struct {
+		testhelper.ID
+		n      int
+		expVal string
+	}{
+		{
+			ID:     testhelper.MkID("one line"),
+			n:      1,
+			expVal: "a\n",
+		},
+		{
+			ID:     testhelper.MkID("more lines than the contents"),
+			n:      5,
+			expVal: contents,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "lines",
+			firstLines(contents, tc.n), tc.expVal)
+	}
+}
+
+func TestMakeTemplateThenCheck(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	files := map[string]string{
+		"main.go":               "package main\n\n// prog does things\n",
+		gitignoreFileName:       "/prog\n",
+		".env":                  "A=1\n",
+		projectConfigFileName:   "permissions=0644\n",
+		filepath.Join("d", "x"): "x\n",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join("prog", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.out = &out
+	prog.dir = "prog"
+	prog.name = "prog"
+	prog.newTemplateDir = "tmpl"
+	prog.addAllMacros()
+
+	prog.MakeTemplate()
+
+	if prog.exitStatus != 0 {
+		t.Fatalf("cannot make the template: %s", out.String())
+	}
+
+	_, err := os.Stat(filepath.Join("tmpl", projectConfigFileName))
+	if err == nil {
+		t.Errorf("the project config file has been copied into the template")
+	}
+
+	prog = NewProg()
+	prog.out = &out
+	prog.action = aCheck
+	prog.strict = true
+	prog.dir = "prog"
+	prog.name = "prog"
+	prog.templateFS = os.DirFS("tmpl")
+	prog.walkerBase = "."
+	prog.addAllMacros()
+
+	prog.CheckTargetDir()
+
+	if prog.exitStatus != 0 {
+		t.Log("the program directory fails the checks of its own template")
+		t.Errorf("\t: %s", out.String())
+	}
+}
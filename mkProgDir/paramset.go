@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/nickwells/param.mod/v7/param"
 	"github.com/nickwells/param.mod/v7/paramset"
 	"github.com/nickwells/verbose.mod/verbose"
@@ -16,6 +18,7 @@ func makeParamSet(prog *Prog) *param.PSet {
 
 		addParams(prog),
 		addNotes(prog),
+		addConfigFiles(os.Args[1:]),
 
 		param.SetProgramDescription(
 			"This will populate a directory with files suitable to form a"+
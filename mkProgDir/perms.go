@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+type permsCheckMode string
+
+const (
+	pcmExact   = permsCheckMode("exact")
+	pcmUmask   = permsCheckMode("umask")
+	pcmNoWider = permsCheckMode("no-wider")
+)
+
+// expectedPerms returns the permissions that a file or directory is
+// expected to have. If the permissions check mode is pcmUmask this is the
+// given permissions with the umask applied, otherwise it is the given
+// permissions unchanged.
+func (prog *Prog) expectedPerms(perms fs.FileMode) fs.FileMode {
+	if prog.permsCheckMode == pcmUmask {
+		return perms &^ prog.umask
+	}
+
+	return perms
+}
+
+// permsOK returns true if the actual permissions are acceptable given the
+// expected permissions and the permissions check mode. If the mode is
+// pcmNoWider the actual permissions are acceptable if they do not have any
+// permission that is not in the expected permissions, otherwise they must
+// match exactly.
+func (prog *Prog) permsOK(exp, act fs.FileMode) bool {
+	if prog.permsCheckMode == pcmNoWider {
+		return act&^exp == 0
+	}
+
+	return act == exp
+}
+
+// CheckPerms checks that the expected permissions match the actual and
+// report any discrepancies, setting the exit status. This is not done if
+// the checkPerms flag is not set. How the permissions are compared depends
+// on the permissions check mode.
+func (prog *Prog) CheckPerms(pathType, path string, perms, act fs.FileMode) {
+	if !prog.checkPerms {
+		return
+	}
+
+	exp := prog.expectedPerms(perms)
+	if prog.permsOK(exp, act) {
+		return
+	}
+
+	if prog.permsCheckMode == pcmNoWider {
+		fmt.Fprintf(prog.out, "%s: %q has wider permissions than expected\n",
+			pathType, path)
+		fmt.Fprintf(prog.out, "\t   extra permissions %04o\n", act&^exp)
+	} else {
+		fmt.Fprintf(prog.out,
+			"%s: %q has unexpected permissions\n", pathType, path)
+	}
+
+	if exp != perms {
+		fmt.Fprintf(prog.out,
+			"\texpected permissions %04o (%04o with umask %04o)\n",
+			exp, perms, prog.umask)
+	} else {
+		fmt.Fprintf(prog.out, "\texpected permissions %04o\n", exp)
+	}
+
+	fmt.Fprintf(prog.out, "\t  actual permissions %04o\n", act)
+	prog.SetExitStatus(esPerms)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestPermsOK(t *testing.T) {
+	const umask = 0o022
+
+	testCases := []struct {
+		testhelper.ID
+		mode  permsCheckMode
+		perms fs.FileMode
+		act   fs.FileMode
+		expOK bool
+	}{
+		{
+			ID:    testhelper.MkID("exact - match"),
+			mode:  pcmExact,
+			perms: 0o664,
+			act:   0o664,
+			expOK: true,
+		},
+		{
+			ID:    testhelper.MkID("exact - umask applied"),
+			mode:  pcmExact,
+			perms: 0o664,
+			act:   0o644,
+		},
+		{
+			ID:    testhelper.MkID("umask - umask applied"),
+			mode:  pcmUmask,
+			perms: 0o664,
+			act:   0o644,
+			expOK: true,
+		},
+		{
+			ID:    testhelper.MkID("umask - umask not applied"),
+			mode:  pcmUmask,
+			perms: 0o664,
+			act:   0o664,
+		},
+		{
+			ID:    testhelper.MkID("no-wider - fewer permissions"),
+			mode:  pcmNoWider,
+			perms: 0o664,
+			act:   0o600,
+			expOK: true,
+		},
+		{
+			ID:    testhelper.MkID("no-wider - extra permissions"),
+			mode:  pcmNoWider,
+			perms: 0o664,
+			act:   0o666,
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := NewProg()
+		prog.permsCheckMode = tc.mode
+		prog.umask = umask
+
+		testhelper.DiffBool(t, tc.IDStr(), "permsOK",
+			prog.permsOK(prog.expectedPerms(tc.perms), tc.act), tc.expOK)
+	}
+}
+
+func TestCheckPermsExitStatus(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		checkPerms    bool
+		act           fs.FileMode
+		expExitStatus int
+	}{
+		{
+			ID:         testhelper.MkID("match"),
+			checkPerms: true,
+			act:        0o664,
+		},
+		{
+			ID:            testhelper.MkID("mismatch"),
+			checkPerms:    true,
+			act:           0o600,
+			expExitStatus: esPerms,
+		},
+		{
+			ID:  testhelper.MkID("mismatch - not checked"),
+			act: 0o600,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.checkPerms = tc.checkPerms
+
+		prog.CheckPerms("File", "a.txt", 0o664, tc.act)
+
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
+
+func TestSetExitStatus(t *testing.T) {
+	prog := NewProg()
+
+	for _, es := range []int{esContent, esPerms, esTemplate, esFailure} {
+		prog.SetExitStatus(es)
+	}
+
+	testhelper.DiffInt(t, "highest priority", "exit status",
+		prog.exitStatus, esTemplate)
+}
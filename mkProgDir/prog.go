@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"slices"
-	"strings"
+	"sync"
 
 	"github.com/nickwells/macros.mod/macros"
 	"github.com/nickwells/verbose.mod/verbose"
@@ -19,11 +22,20 @@ const (
 	aCreate = action("create")
 	aCheck  = action("check")
 	aFix    = action("fix")
+
+	aLintTemplate = action("lint-template")
+	aTestTemplate = action("test-template")
+	aMakeTemplate = action("make-template")
+	aDescTemplate = action("describe-template")
+	aUndo         = action("undo")
 )
 
 // Prog holds program parameters and status
 type Prog struct {
 	exitStatus int
+	in         io.Reader
+	out        io.Writer
+	jobs       int
 
 	stack *verbose.Stack
 
@@ -34,16 +46,51 @@ type Prog struct {
 	walkerBase      string
 	templateDirName string
 	templateFS      fs.FS
+	templateOnDisk  bool
+
+	newTemplateDir   string
+	beginsCheckLines int
+
+	reportFormat reportFormat
+
+	reportAllFiles   bool
+	checkBuild       bool
+	strict           bool
+	warningsAsErrors bool
+	failFast         bool
+	quiet            bool
+	recursive        bool
+
+	checkPerms     bool
+	permsCheckMode permsCheckMode
+	umask          fs.FileMode
+	filePerms      fs.FileMode
+	filePermsGiven bool
+	dirPerms       fs.FileMode
+
+	fileChecks map[string][]fileCheck
 
-	reportAllFiles bool
+	targets      []string
+	requirements []modRequirement
 
-	checkPerms bool
-	filePerms  fs.FileMode
-	dirPerms   fs.FileMode
+	modulePath     string
+	addToWorkspace bool
+	modFilesMu     *sync.Mutex
 
-	fileChecks map[string][]checkContentFunc
+	runHooks bool
+	hooks    map[hookEvent][]hookCmd
 
+	interactive     bool
+	onConflict      conflictPolicy
+	intoExistingDir bool
+
+	macroDefs  []string
 	macroCache *macros.Cache
+
+	journal *journal
+
+	stats   taskStats
+	summary checkSummary
 }
 
 // NewProg returns a new Prog instance with the default values set
@@ -62,22 +109,33 @@ func NewProg() *Prog {
 	}
 
 	return &Prog{
+		in:              os.Stdin,
+		out:             os.Stdout,
+		jobs:            runtime.GOMAXPROCS(0),
+		modFilesMu:      &sync.Mutex{},
 		filePerms:       0o664, // rw-rw-r--
 		dirPerms:        0o775, // rwxrwxr-x
+		permsCheckMode:  pcmExact,
+		reportFormat:    rfTable,
+		onConflict:      ocFail,
 		action:          aCreate,
 		walkerBase:      tmpl.name,
 		templateDirName: tmpl.name,
 		templateFS:      tmpl.fs,
-		fileChecks:      map[string][]checkContentFunc{},
+		fileChecks:      map[string][]fileCheck{},
+		runHooks:        true,
+		hooks:           map[hookEvent][]hookCmd{},
 		macroCache:      mc,
+		journal:         &journal{},
 		stack:           &verbose.Stack{},
 	}
 }
 
 // SetExitStatus sets the exit status to the new value. It will not do this
-// if the exit status has already been set to a non-zero value.
+// if the exit status has already been set to a value with a higher
+// priority (see the exit status constants).
 func (prog *Prog) SetExitStatus(es int) {
-	if prog.exitStatus == 0 {
+	if es > prog.exitStatus {
 		prog.exitStatus = es
 	}
 }
@@ -92,11 +150,8 @@ func (prog *Prog) ForceExitStatus(es int) {
 func (prog *Prog) setFileChecks() {
 	defer prog.stack.Start("setFileChecks", "Start")()
 
-	err := fs.WalkDir(prog.templateFS, prog.walkerBase, prog.makeFileCheck())
-	if err != nil {
-		fmt.Printf("Problem found walking the template directory: %s\n", err)
-		prog.SetExitStatus(1)
-	}
+	wp := prog.newWalkProblems()
+	prog.walkTemplate(wp, prog.makeFileCheck(wp))
 }
 
 // makeFileCheck returns a function used to walk the file system
@@ -108,9 +163,10 @@ func (prog *Prog) setFileChecks() {
 //
 // The remainder is the name of the file to be checked. A check function will
 // be generated from the contents of the file and the value of the check-type
-// suffix and will be added to the list of check funcs for that file.
-func (prog *Prog) makeFileCheck() fs.WalkDirFunc {
-	return func(path string, d fs.DirEntry, err error) error {
+// suffix and will be added to the list of check funcs for that file. Any
+// problems are recorded in the walkProblems.
+func (prog *Prog) makeFileCheck(wp *walkProblems) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) (rval error) {
 		defer prog.stack.Start("makeFileCheck",
 			fmt.Sprintf("Start%25s: %q", "template file", path))()
 
@@ -119,47 +175,79 @@ func (prog *Prog) makeFileCheck() fs.WalkDirFunc {
 		defer (func() {
 			// checkContentMatches can panic if the regexp doesn't compile
 			if panicVal := recover(); panicVal != nil {
-				verbose.Printf("%s PANIC: %v\n", intro, panicVal)
-				err = fmt.Errorf("file checks for %q could not be made: %s",
-					path, panicVal)
+				prog.verbosef("%s PANIC: %v\n", intro, panicVal)
+				rval = wp.add(
+					fmt.Errorf("%q : file checks could not be made: %s",
+						path, panicVal),
+					esTemplate)
 			}
 		})()
 
 		if err != nil {
-			fmt.Println(err)
-			return nil
+			return wp.add(err, esTemplate)
 		}
 
 		tfi, err := prog.getTemplateFileInfo(path, d)
 		if err != nil {
-			fmt.Println(err)
-			return err
+			return wp.add(err, esTemplate)
 		}
 
 		if tfi.isAGenFile {
-			verbose.Printf("%s %30s: %s\n", intro, "", "a generated file")
+			prog.verbosef("%s %30s: %s\n", intro, "", "a generated file")
 		}
 
 		if tfi.isAnOptionalFile {
-			verbose.Printf("%s %30s: %s\n", intro, "", "an optional file")
+			prog.verbosef("%s %30s: %s\n", intro, "", "an optional file")
+		}
+
+		if tfi.isARequiresFile {
+			prog.verbosef("%s %30s: %s\n", intro, "", "a requirements file")
+
+			if err := prog.addRequirementsFile(tfi); err != nil {
+				return wp.add(err, esTemplate)
+			}
+
+			return nil
+		}
+
+		if tfi.isAHook {
+			prog.verbosef("%s %30s: %s\n", intro, "", "a hook file")
+
+			if err := prog.addHook(tfi); err != nil {
+				return wp.add(err, esTemplate)
+			}
+
+			return nil
 		}
 
 		if !tfi.isACheckFile {
-			verboseSkipMsg(intro, "not a check file")
+			if !tfi.isADir && !tfi.isTheTemplateDir {
+				prog.targets = append(prog.targets, tfi.target)
+			}
+
+			prog.verboseSkip(intro, "not a check file")
 			return nil
 		}
 
-		verbose.Printf("%s %30s: %s\n", intro, "check-type",
+		prog.verbosef("%s %30s: %s\n", intro, "check-type",
 			tfi.checkTypeSuffix)
 
 		f, ok := checkTypeMap[tfi.checkTypeSuffix]
 		if !ok {
-			verbose.Printf("%s %30s: %s\n", intro, "", "bad check-type")
-			return fmt.Errorf("bad check-type suffix: %q", tfi.checkTypeSuffix)
+			prog.verbosef("%s %30s: %s\n", intro, "", "bad check-type")
+
+			return wp.add(
+				fmt.Errorf("%q : bad check-type suffix: %q",
+					path, tfi.checkTypeSuffix),
+				esTemplate)
 		}
 
 		prog.fileChecks[tfi.target] = append(prog.fileChecks[tfi.target],
-			f(tfi.contents))
+			fileCheck{
+				checkType: checkTypeName(tfi.checkTypeSuffix),
+				severity:  tfi.severity,
+				check:     f(tfi.contents),
+			})
 
 		return nil
 	}
@@ -172,22 +260,133 @@ func (prog *Prog) Run() {
 
 	prog.addAllMacros()
 
+	prog.umask = getUmask()
+
 	switch prog.action {
 	case aCreate:
-		prog.CreateAllFiles()
+		if prog.interactive {
+			prog.CreateInteractively()
+			return
+		}
+
+		prog.CreateTargetDir()
+
 		return
 	case aCheck, aFix:
-		prog.setFileChecks()
-		prog.CheckAllFiles()
+		if prog.recursive {
+			prog.CheckTree()
+			return
+		}
+
+		prog.CheckTargetDir()
+
+		return
+	case aLintTemplate:
+		prog.LintTemplate()
+
+		return
+	case aTestTemplate:
+		prog.TestTemplate()
+
+		return
+	case aMakeTemplate:
+		prog.MakeTemplate()
+
+		return
+	case aDescTemplate:
+		prog.DescribeTemplate()
+
+		return
+	case aUndo:
+		prog.Undo()
 
 		return
 	}
 
-	fmt.Printf(
+	fmt.Fprintf(prog.out,
 		"Unexpected action: %q - there is no code to handle this action\n",
 		prog.action)
 }
 
+// CreateTargetDir creates the target directory and populates it from the
+// template. The directory is built in a temporary directory and only
+// renamed into place if it is successfully built so that a partially
+// populated target directory is never left behind. If the target directory
+// already exists (only allowed if a conflict policy has been given) it is
+// populated in place with the policy applied to any existing files. Each
+// subsequent step is only taken if the previous steps succeeded. The
+// changes made are recorded in a journal so that they can be undone.
+func (prog *Prog) CreateTargetDir() {
+	if isADir(prog.dir) {
+		prog.intoExistingDir = true
+		prog.createInExistingDir()
+	} else {
+		prog.buildTargetDir()
+	}
+
+	if prog.exitStatus == 0 && prog.addToWorkspace {
+		prog.AddToWorkspace()
+	}
+
+	if prog.exitStatus == 0 {
+		prog.RunHooks(hookPostCreate)
+	}
+
+	prog.writeJournal()
+}
+
+// CheckTargetDir performs all the checks on the target directory, fixing
+// any problems if the action is aFix. Any fixes made are recorded in a
+// journal so that they can be undone. The post-fix hooks are only run if
+// the fix succeeded. A summary of the checks is reported last; if the
+// quiet parameter has been given nothing else is reported.
+func (prog *Prog) CheckTargetDir() {
+	out := prog.out
+	if prog.quiet {
+		prog.out = io.Discard
+	}
+
+	prog.setFileChecks()
+	prog.CheckAllFiles()
+
+	if prog.strict {
+		prog.runDirCheck(prog.CheckStrayFiles)
+	}
+
+	prog.runDirCheck(prog.CheckRequirements)
+	prog.runDirCheck(prog.CheckWorkspace)
+
+	if prog.action == aFix {
+		if prog.exitStatus == 0 {
+			prog.RunHooks(hookPostFix)
+		}
+
+		prog.writeJournal()
+	}
+
+	if prog.checkBuild {
+		prog.runDirCheck(prog.CheckBuild)
+	}
+
+	prog.out = out
+	prog.reportCheckSummary()
+}
+
+// runDirCheck runs a check of the target directory as a whole and counts
+// it in the summary if it fails
+func (prog *Prog) runDirCheck(check func()) {
+	es := prog.exitStatus
+	prog.exitStatus = esOK
+
+	check()
+
+	if prog.exitStatus != esOK {
+		prog.summary.dirFailed++
+	}
+
+	prog.SetExitStatus(es)
+}
+
 // CreateAllFiles creates the directory and all the files that it should
 // contain.
 func (prog *Prog) CreateAllFiles() {
@@ -199,37 +398,23 @@ func (prog *Prog) CreateAllFiles() {
 
 	err := os.MkdirAll(prog.dir, prog.dirPerms)
 	if err != nil {
-		fmt.Printf("Cannot create the program directory (%q): %s\n",
+		fmt.Fprintf(prog.out, "Cannot create the program directory (%q): %s\n",
 			prog.dir, err)
-		prog.SetExitStatus(1)
+		prog.SetExitStatus(esIOError)
 
 		return
 	}
 
 	verbose.Println(intro, " walking the template directory")
 
-	err = fs.WalkDir(prog.templateFS, prog.walkerBase, prog.createFileFunc())
-	if err != nil {
-		fmt.Printf("Problem found walking the template directory: %s\n", err)
-		prog.SetExitStatus(1)
-	}
-}
-
-// CheckPerms checks that the expected permissions match the actual and
-// report any discrepancies. This is not done if the checkPerms flag is not
-// set.
-func (prog *Prog) CheckPerms(pathType, path string, exp, act fs.FileMode) {
-	if prog.checkPerms && act != exp {
-		fmt.Printf("%s: %q has unexpected permissions\n", pathType, path)
-		fmt.Printf("\texpected permissions %04o\n", exp)
-		fmt.Printf("\t  actual permissions %04o\n", act)
-	}
+	wp := prog.newWalkProblems()
+	prog.walkTemplate(wp, prog.createFileFunc(wp))
 }
 
 // CheckDir checks that the named directory exists and has the expected
 // permissions. It reports any errors and returns false if the path does not
 // refer to a Stat-able directory
-func (prog *Prog) CheckDir(path string) bool {
+func (prog *Prog) CheckDir(path string, perms fs.FileMode) bool {
 	defer prog.stack.Start("CheckDir",
 		fmt.Sprintf("Start%25s: %q", "directory to check", path))()
 
@@ -238,30 +423,32 @@ func (prog *Prog) CheckDir(path string) bool {
 	fi, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			fmt.Printf("directory %q does not exist\n", path)
-			prog.SetExitStatus(1)
+			fmt.Fprintf(prog.out, "directory %q does not exist\n", path)
+			prog.SetExitStatus(esMissing)
+			prog.stats.missing = true
 
 			return false
 		}
 
-		fmt.Printf("Cannot check the directory (%q):\n\t%s\n", path, err)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out,
+			"Cannot check the directory (%q):\n\t%s\n", path, err)
+		prog.SetExitStatus(esIOError)
 
 		return false
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "directory exists")
+	prog.verbosef("%s %30s: %s\n", intro, "", "directory exists")
 
 	if !fi.Mode().IsDir() {
-		fmt.Printf("%q is not a directory\n", path)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out, "%q is not a directory\n", path)
+		prog.SetExitStatus(esMissing)
 
 		return false
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "is a directory")
+	prog.verbosef("%s %30s: %s\n", intro, "", "is a directory")
 
-	prog.CheckPerms("Directory", path, prog.dirPerms, fi.Mode()&fs.ModePerm)
+	prog.CheckPerms("Directory", path, perms, fi.Mode()&fs.ModePerm)
 
 	return true
 }
@@ -278,19 +465,26 @@ func (prog *Prog) ReportStatErr(tfi TemplateFileInfo, err error) {
 
 	if os.IsNotExist(err) {
 		if prog.action == aFix {
-			verbose.Printf("%s %30s: %q\n",
+			prog.verbosef("%s %30s: %q\n",
 				intro, "fixing missing file", tfi.target)
 
-			_ = prog.CreateTargetFile(tfi)
+			if prog.CreateTarget(tfi) == nil {
+				prog.stats.fixed = true
+
+				fmt.Fprintf(prog.out,
+					"%q did not exist, it has been created\n", path)
+			}
 
 			return
 		}
 
 		isOpt := ""
+		prog.stats.missing = !tfi.isAnOptionalFile
+		prog.stats.optMissing = tfi.isAnOptionalFile
 
 		if tfi.isAnOptionalFile {
 			if !prog.reportAllFiles {
-				verbose.Printf("%s %30s: %s\n",
+				prog.verbosef("%s %30s: %s\n",
 					intro, "", "file does not exist but is optional")
 
 				return
@@ -299,18 +493,25 @@ func (prog *Prog) ReportStatErr(tfi TemplateFileInfo, err error) {
 			isOpt = " (is optional)"
 		}
 
-		fmt.Printf("%q does not exist%s\n", path, isOpt)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out, "%q does not exist%s\n", path, isOpt)
+		prog.SetExitStatus(esMissing)
 
 		return
 	}
 
-	fmt.Printf("Cannot check the file (%q):\n\t%s\n", path, err)
-	prog.SetExitStatus(1)
+	fmt.Fprintf(prog.out, "Cannot check the file (%q):\n\t%s\n", path, err)
+	prog.SetExitStatus(esIOError)
 }
 
 // CheckContents applies the associated file checks to the contents of the
-// file
+// file. A failed check is reported with its severity and only sets the
+// exit status if the severity is that of a problem. Checks suppressed by a
+// directive in the file are still applied but a failure is reported
+// separately and does not set the exit status. Any directive which gives
+// no reason or which no longer suppresses a failed check is reported as a
+// problem. Every check is applied so that all the failures are reported
+// unless the fail-fast parameter has been given, in which case the checks
+// stop at the first failure.
 func (prog *Prog) CheckContents(tfi TemplateFileInfo, contents string) {
 	path := tfi.target
 	defer prog.stack.Start("CheckFileContents",
@@ -318,20 +519,67 @@ func (prog *Prog) CheckContents(tfi TemplateFileInfo, contents string) {
 
 	intro := prog.stack.Tag()
 
-	chkFuncs := prog.fileChecks[tfi.target]
-	if len(chkFuncs) == 0 {
+	fileChecks := prog.fileChecks[tfi.target]
+	if len(fileChecks) == 0 {
 		return
 	}
 
-	for _, cf := range chkFuncs {
-		s := cf(path, contents)
-		if s != 0 {
-			prog.SetExitStatus(1)
-			return
+	sups, problems := parseSuppressions(contents)
+	checked := map[string]bool{}
+	suppressed := []string{}
+	failed := false
+	stopped := false
+
+	for _, fc := range fileChecks {
+		checked[fc.checkType] = true
+		prog.stats.checksRun++
+
+		sup := suppressedBy(sups, fc.checkType)
+		if sup == nil {
+			var failure bytes.Buffer
+
+			if fc.check(&failure, path, contents) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(prog.out, "%s: %s", fc.severity, failure.String())
+
+			if prog.failsCheck(fc.severity) {
+				failed = true
+
+				if prog.failFast {
+					stopped = true
+					break
+				}
+			}
+
+			continue
 		}
+
+		if fc.check(io.Discard, path, contents) != 0 {
+			sup.used[fc.checkType] = true
+			suppressed = append(suppressed,
+				fmt.Sprintf("%s (line %d): %s",
+					fc.checkType, sup.line, sup.reasonOrDflt()))
+		}
+	}
+
+	if !stopped {
+		problems = append(problems, unusedSuppressions(sups, checked)...)
+	}
+
+	reportSuppressed(prog.out, path, suppressed)
+
+	for _, p := range problems {
+		fmt.Fprintf(prog.out, "%q : %s\n", path, p)
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "contents OK")
+	if failed || len(problems) > 0 {
+		prog.SetExitStatus(esContent)
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "contents OK")
 }
 
 // CheckFile checks that the given file exists, is a file, has the correct
@@ -349,28 +597,28 @@ func (prog *Prog) CheckFile(tfi TemplateFileInfo) {
 		return
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "file exists")
+	prog.verbosef("%s %30s: %s\n", intro, "", "file exists")
 
 	if !fi.Mode().IsRegular() {
-		fmt.Printf("%q is not a regular file\n", path)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out, "%q is not a regular file\n", path)
+		prog.SetExitStatus(esMissing)
 
 		return
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "is a regular file")
+	prog.verbosef("%s %30s: %s\n", intro, "", "is a regular file")
 
-	prog.CheckPerms("File", path, prog.filePerms, fi.Mode()&fs.ModePerm)
+	prog.CheckPerms("File", path, tfi.perms, fi.Mode()&fs.ModePerm)
 
 	contents, err := os.ReadFile(path) //nolint:gosec
 	if err != nil {
-		fmt.Printf("File: %q can't be read: %s", path, err)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out, "File: %q can't be read: %s", path, err)
+		prog.SetExitStatus(esIOError)
 
 		return
 	}
 
-	verbose.Printf("%s %30s: %s\n", intro, "", "can be read")
+	prog.verbosef("%s %30s: %s\n", intro, "", "can be read")
 
 	prog.CheckContents(tfi, string(contents))
 }
@@ -382,22 +630,46 @@ func (prog *Prog) CheckAllFiles() {
 
 	intro := prog.stack.Tag()
 
-	if !prog.CheckDir(prog.dir) {
+	if !prog.CheckDir(prog.dir, prog.dirPerms) {
+		prog.summary.dirFailed++
 		return
 	}
 
-	verbose.Println(intro, " walking the template directory")
+	prog.verbosef("%s  walking the template directory\n", intro)
 
-	err := fs.WalkDir(prog.templateFS, prog.walkerBase, prog.checkFileFunc())
-	if err != nil {
-		fmt.Printf("Problem found walking the template directory: %s\n", err)
-		prog.SetExitStatus(1)
+	tasks := []TemplateFileInfo{}
+
+	wp := prog.newWalkProblems()
+	prog.walkTemplate(wp, prog.checkFileFunc(wp, &tasks))
+
+	prog.verbosef("%s %30s: %d\n", intro, "targets to check", len(tasks))
+
+	rpt := runTasks(prog, tasks,
+		func(tfi TemplateFileInfo) string { return tfi.target },
+		(*Prog).CheckTarget)
+
+	prog.summary.addEntries(rpt.Entries())
+}
+
+// CheckTarget checks the target given by the template file info, it may be
+// a directory, a symbolic link or a file.
+func (prog *Prog) CheckTarget(tfi TemplateFileInfo) {
+	switch {
+	case tfi.isADir:
+		prog.CheckDir(tfi.target, tfi.perms)
+	case tfi.isASymlink:
+		prog.CheckSymlink(tfi)
+	default:
+		prog.CheckFile(tfi)
 	}
 }
 
-// checkFileFunc returns a function that will check that a file in the
-// template directory is present in the target directory
-func (prog *Prog) checkFileFunc() fs.WalkDirFunc {
+// checkFileFunc returns a function that will record, in the list of tasks,
+// those files in the template directory that should be present in the
+// target directory. Any problems are recorded in the walkProblems.
+func (prog *Prog) checkFileFunc(
+	wp *walkProblems, tasks *[]TemplateFileInfo,
+) fs.WalkDirFunc {
 	return func(path string, d fs.DirEntry, err error) error {
 		defer prog.stack.Start("checkFileFunc",
 			fmt.Sprintf("Start%25s: %q", "template file", path))()
@@ -405,58 +677,82 @@ func (prog *Prog) checkFileFunc() fs.WalkDirFunc {
 		intro := prog.stack.Tag()
 
 		if err != nil {
-			fmt.Println(err)
-			prog.SetExitStatus(1)
-
-			return nil
+			return wp.add(err, esTemplate)
 		}
 
 		tfi, err := prog.getTemplateFileInfo(path, d)
 		if err != nil {
-			fmt.Println(err)
-			return err
+			return wp.add(err, esTemplate)
 		}
 
 		if tfi.isTheTemplateDir {
-			verboseSkipMsg(intro, "is the template dir")
+			prog.verboseSkip(intro, "is the template dir")
 			return nil
 		}
 
 		if tfi.isACheckFile {
-			verboseSkipMsg(intro, "is a check file")
+			prog.verboseSkip(intro, "is a check file")
 			return nil
 		}
 
-		if tfi.isAGenFile {
-			verbose.Printf("%s %30s: %s\n", intro, "", "a generated file")
+		if tfi.isARequiresFile {
+			prog.verboseSkip(intro, "is a requirements file")
+			return nil
 		}
 
-		if tfi.isADir {
-			prog.CheckDir(tfi.target)
+		if tfi.isAHook {
+			prog.verboseSkip(intro, "is a hook file")
 			return nil
 		}
 
-		prog.CheckFile(tfi)
+		if tfi.isAGenFile {
+			prog.verbosef("%s %30s: %s\n", intro, "", "a generated file")
+		}
+
+		*tasks = append(*tasks, tfi)
 
 		return nil
 	}
 }
 
+// CreateTarget creates the target, either a symbolic link or a file
+// depending on the template file info.
+func (prog *Prog) CreateTarget(tfi TemplateFileInfo) error {
+	before := prog.journalBefore(tfi.target)
+
+	var err error
+
+	if tfi.isASymlink {
+		err = prog.CreateTargetSymlink(tfi)
+	} else {
+		err = prog.CreateTargetFile(tfi)
+	}
+
+	if err == nil {
+		prog.journalAfter(tfi.target, before)
+	}
+
+	return err
+}
+
 // CreateTargetFile creates the target file, filling it with the template
-// contents
+// contents. The file is written to a temporary file which is then renamed
+// so that the target is never left partially written.
 func (prog *Prog) CreateTargetFile(tfi TemplateFileInfo) error {
-	err := os.WriteFile(tfi.target, []byte(tfi.contents), prog.filePerms)
+	err := writeFileAtomic(tfi.target, []byte(tfi.contents),
+		tfi.perms&^prog.umask)
 	if err != nil {
-		fmt.Printf("Can't create %q: %s\n", tfi.target, err)
-		prog.SetExitStatus(1)
+		fmt.Fprintf(prog.out, "Can't create %q: %s\n", tfi.target, err)
+		prog.SetExitStatus(esIOError)
 	}
 
 	return err
 }
 
 // createFileFunc returns a function that will copy a file from the template
-// directory into the target directory or generate it from the template file
-func (prog *Prog) createFileFunc() fs.WalkDirFunc {
+// directory into the target directory or generate it from the template
+// file. Any problems are recorded in the walkProblems.
+func (prog *Prog) createFileFunc(wp *walkProblems) fs.WalkDirFunc {
 	return func(path string, d fs.DirEntry, err error) error {
 		defer prog.stack.Start("createFileFunc",
 			fmt.Sprintf("Start%25s: %q", "template file", path))()
@@ -464,14 +760,12 @@ func (prog *Prog) createFileFunc() fs.WalkDirFunc {
 		intro := prog.stack.Tag()
 
 		if err != nil {
-			fmt.Println("Error walking the template directory: ", err)
-			return nil
+			return wp.add(err, esTemplate)
 		}
 
 		tfi, err := prog.getTemplateFileInfo(path, d)
 		if err != nil {
-			fmt.Println("Error getting the template file info: ", err)
-			return err
+			return wp.add(err, esTemplate)
 		}
 
 		if tfi.isTheTemplateDir {
@@ -484,32 +778,80 @@ func (prog *Prog) createFileFunc() fs.WalkDirFunc {
 			return nil
 		}
 
+		if tfi.isARequiresFile {
+			verboseSkipMsg(intro, "is a requirements file")
+
+			if err := prog.addRequirementsFile(tfi); err != nil {
+				return wp.add(err, esTemplate)
+			}
+
+			return nil
+		}
+
+		if tfi.isAHook {
+			verboseSkipMsg(intro, "is a hook file")
+
+			if err := prog.addHook(tfi); err != nil {
+				return wp.add(err, esTemplate)
+			}
+
+			return nil
+		}
+
 		if tfi.isAGenFile {
 			verbose.Printf("%s %30s: %s\n", intro, "", "a generated file")
 		}
 
 		verbose.Printf("%s %30s: %q\n", intro, "file to create", tfi.target)
 
+		doneMsg := ""
+
+		if prog.intoExistingDir {
+			var create bool
+
+			create, doneMsg = prog.resolveConflict(&tfi)
+			if !create {
+				if tfi.isADir && !isADir(tfi.target) {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+		}
+
 		if tfi.isADir {
-			err = os.Mkdir(tfi.target, prog.dirPerms)
+			err = os.Mkdir(tfi.target, tfi.perms)
 			if err != nil {
-				fmt.Printf("Can't create directory %q: %s\n", tfi.target, err)
-				prog.SetExitStatus(1)
+				if err := wp.add(
+					fmt.Errorf("%q : can't create directory %q: %w",
+						path, tfi.target, err),
+					esIOError); err != nil {
+					return err
+				}
+
+				return fs.SkipDir
 			}
 
-			return err
+			prog.journalAfter(tfi.target, nil)
+
+			fmt.Fprint(prog.out, doneMsg)
+
+			return nil
 		}
 
-		err = prog.CreateTargetFile(tfi)
+		err = prog.CreateTarget(tfi)
 		if err != nil {
-			fmt.Printf("Can't create %q: %s\n", tfi.target, err)
-			prog.SetExitStatus(1)
-
-			return err
+			return wp.add(
+				fmt.Errorf("%q : can't create %q: %w", path, tfi.target, err),
+				esIOError)
 		}
 
 		verbose.Printf("%s %30s: %s\n", intro, "", "file created")
 
+		fmt.Fprint(prog.out, doneMsg)
+
+		prog.targets = append(prog.targets, tfi.target)
+
 		return nil
 	}
 }
@@ -519,13 +861,16 @@ func (prog *Prog) createFileFunc() fs.WalkDirFunc {
 // System which uses '/' as a file separator regardless of the separator used
 // in the target File System.
 //
-// - The supplied path is first split into its component parts
-// - then the first part (the name of the template directory) is removed
+// - The template directory is first removed from the supplied path
+// - then the remaining path is split into its component parts
 // - then the target directory name is added at the beginning
 // - finally the new path name is built using the appropriate separator
 func (prog Prog) makeNewPath(path string) string {
-	path = strings.TrimPrefix(path, prog.walkerBase)
-	pathParts := splitPath(path)
+	if path == prog.walkerBase {
+		return prog.dir
+	}
+
+	pathParts := splitPath(prog.templateRelPath(path))
 
 	pathParts = slices.Insert[[]string, string](pathParts, 0, prog.dir)
 
@@ -551,3 +896,19 @@ func splitPath(fsPath string) []string {
 func verboseSkipMsg(intro, reason string) {
 	verbose.Printf("%s %30s: ** Skipping ** - %s\n", intro, "", reason)
 }
+
+// verbosef prints the message to the program output if verbose output is
+// on. For a task the program output is the task's report buffer and so,
+// unlike verbose.Printf, the message is not interleaved with the output of
+// other tasks.
+func (prog *Prog) verbosef(format string, a ...any) {
+	if verbose.IsOn() {
+		fmt.Fprintf(prog.out, format, a...)
+	}
+}
+
+// verboseSkip prints a skipping message, as for verboseSkipMsg, using
+// verbosef
+func (prog *Prog) verboseSkip(intro, reason string) {
+	prog.verbosef("%s %30s: ** Skipping ** - %s\n", intro, "", reason)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// reportEntry records the output, the exit status and the statistics from
+// checking a single target
+type reportEntry struct {
+	path       string
+	output     bytes.Buffer
+	exitStatus int
+	stats      taskStats
+}
+
+// report collects the entries from the checks of the targets. It is safe
+// for concurrent use.
+type report struct {
+	mu      sync.Mutex
+	entries []*reportEntry
+}
+
+// Add adds the entry to the report
+func (r *report) Add(re *reportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, re)
+}
+
+// sortedEntries returns the entries sorted by path
+func (r *report) sortedEntries() []*reportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := slices.Clone(r.entries)
+	slices.SortStableFunc(entries, func(a, b *reportEntry) int {
+		return cmp.Compare(a.path, b.path)
+	})
+
+	return entries
+}
+
+// Entries returns the entries in the report sorted by path
+func (r *report) Entries() []*reportEntry {
+	return r.sortedEntries()
+}
+
+// Write writes the output from each entry in the report, in order of the
+// path, to the writer
+func (r *report) Write(w io.Writer) error {
+	for _, re := range r.sortedEntries() {
+		if _, err := w.Write(re.output.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExitStatus returns the exit status of the entries with the highest
+// priority. It returns zero if all the entries have a zero exit status.
+func (r *report) ExitStatus() int {
+	es := esOK
+
+	for _, re := range r.sortedEntries() {
+		es = max(es, re.exitStatus)
+	}
+
+	return es
+}
+
+// forTask returns a copy of the Prog which will write its output to the
+// report entry. It has its own exit status and verbose stack so that it
+// can be used concurrently with other copies. The values shared with the
+// original Prog must not be changed while checking.
+func (prog *Prog) forTask(re *reportEntry) *Prog {
+	tp := *prog
+	tp.exitStatus = 0
+	tp.stats = taskStats{}
+	tp.out = &re.output
+	tp.stack = &verbose.Stack{ShowTimings: prog.stack.ShowTimings}
+
+	return &tp
+}
+
+// runTasks runs the task function for each of the items using a pool of
+// workers, the number of workers being given by the jobs parameter. Each
+// task is run against a copy of the Prog which records its output and exit
+// status in a report entry. Once all the tasks have completed, the output
+// of the tasks is written in order of the path of each item and the exit
+// status is set. If the fail-fast parameter has been given no further tasks
+// are started once a task has failed; tasks already running are
+// completed. If the quiet parameter has been given the output of the tasks
+// is not written. The report is returned.
+func runTasks[T any](prog *Prog, items []T,
+	pathOf func(T) string, task func(*Prog, T),
+) *report {
+	rpt := &report{}
+	itemCh := make(chan T)
+
+	var (
+		wg      sync.WaitGroup
+		stopped atomic.Bool
+	)
+
+	for range max(1, min(prog.jobs, len(items))) {
+		wg.Go(func() {
+			for item := range itemCh {
+				if stopped.Load() {
+					continue
+				}
+
+				re := &reportEntry{path: pathOf(item)}
+				tp := prog.forTask(re)
+
+				task(tp, item)
+
+				re.exitStatus = tp.exitStatus
+				re.stats = tp.stats
+				rpt.Add(re)
+
+				if prog.failFast && re.exitStatus != esOK {
+					stopped.Store(true)
+				}
+			}
+		})
+	}
+
+	for _, item := range items {
+		if stopped.Load() {
+			break
+		}
+
+		itemCh <- item
+	}
+
+	close(itemCh)
+	wg.Wait()
+
+	if !prog.quiet {
+		if err := rpt.Write(prog.out); err != nil {
+			prog.SetExitStatus(esIOError)
+		}
+	}
+
+	prog.SetExitStatus(rpt.ExitStatus())
+
+	return rpt
+}
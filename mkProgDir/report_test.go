@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRunTasks(t *testing.T) {
+	items := []string{"e", "d/x", "c", "b", "a"}
+
+	for _, jobs := range []int{1, 2, 8} {
+		id := fmt.Sprintf("jobs: %d", jobs)
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.jobs = jobs
+
+		rpt := runTasks(prog, items,
+			func(s string) string { return s },
+			func(tp *Prog, s string) {
+				fmt.Fprintf(tp.out, "checked %s\n", s)
+
+				if s == "d/x" || s == "b" {
+					tp.SetExitStatus(len(s))
+				}
+			})
+
+		testhelper.DiffString(t, id, "output", out.String(),
+			"checked a\n"+
+				"checked b\n"+
+				"checked c\n"+
+				"checked d/x\n"+
+				"checked e\n")
+		// the highest exit status is used
+		testhelper.DiffInt(t, id, "exit status", prog.exitStatus, 3)
+		testhelper.DiffInt(t, id, "report entries", len(rpt.Entries()),
+			len(items))
+	}
+}
+
+func TestRunTasksFailFastQuiet(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		failFast   bool
+		quiet      bool
+		expOut     string
+		expEntries int
+	}{
+		{
+			ID:         testhelper.MkID("all tasks"),
+			expOut:     "checked a\nchecked b\nchecked c\n",
+			expEntries: 3,
+		},
+		{
+			ID:         testhelper.MkID("fail fast"),
+			failFast:   true,
+			expOut:     "checked a\nchecked b\n",
+			expEntries: 2,
+		},
+		{
+			ID:         testhelper.MkID("quiet"),
+			quiet:      true,
+			expEntries: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.jobs = 1
+		prog.failFast = tc.failFast
+		prog.quiet = tc.quiet
+
+		rpt := runTasks(prog, []string{"a", "b", "c"},
+			func(s string) string { return s },
+			func(tp *Prog, s string) {
+				fmt.Fprintf(tp.out, "checked %s\n", s)
+
+				if s == "b" {
+					tp.SetExitStatus(esContent)
+				}
+			})
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esContent)
+		testhelper.DiffInt(t, tc.IDStr(), "report entries",
+			len(rpt.Entries()), tc.expEntries)
+	}
+}
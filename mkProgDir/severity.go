@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type severity string
+
+const (
+	sevError   = severity("error")
+	sevWarning = severity("warning")
+	sevInfo    = severity("info")
+)
+
+// severityDescs gives the meaning of each severity
+var severityDescs = []struct {
+	sev  severity
+	desc string
+}{
+	{sevError, "a failed check is a problem, this is the default"},
+	{sevWarning, "a failed check is reported but is only a problem" +
+		" if warnings are to be treated as errors"},
+	{sevInfo, "a failed check is reported for information only"},
+}
+
+// sfxSeverity introduces the severity of a check, it comes before the check
+// suffix
+const sfxSeverity = "--mkProgDir-Severity-"
+
+// the severity suffix followed by the severity at the end of the string
+var severityRE = regexp.MustCompile(sfxSeverity + `([a-zA-Z]*)$`)
+
+// trimSeveritySuffix strips the trailing severity suffix if present and
+// returns the stripped path and the severity. If there is no severity
+// suffix it returns the path unchanged and the default severity. An error
+// is returned if the severity is not recognised.
+func trimSeveritySuffix(path string) (string, severity, error) {
+	m := severityRE.FindStringSubmatch(path)
+	if m == nil {
+		return path, sevError, nil
+	}
+
+	sev := severity(m[1])
+	for _, sd := range severityDescs {
+		if sd.sev == sev {
+			return strings.TrimSuffix(path, m[0]), sev, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unknown severity: %q", m[1])
+}
+
+// failsCheck returns true if a failed check of the given severity should
+// cause the check of the target directory to fail
+func (prog *Prog) failsCheck(sev severity) bool {
+	switch sev {
+	case sevWarning:
+		return prog.warningsAsErrors
+	case sevInfo:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTrimSeveritySuffix(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		path    string
+		expPath string
+		expSev  severity
+	}{
+		{
+			ID:      testhelper.MkID("no severity"),
+			path:    "main.go.begins",
+			expPath: "main.go.begins",
+			expSev:  sevError,
+		},
+		{
+			ID:      testhelper.MkID("warning"),
+			path:    "main.go.begins.1" + sfxSeverity + "warning",
+			expPath: "main.go.begins.1",
+			expSev:  sevWarning,
+		},
+		{
+			ID:      testhelper.MkID("info"),
+			path:    "main.go.begins" + sfxSeverity + "info",
+			expPath: "main.go.begins",
+			expSev:  sevInfo,
+		},
+		{
+			ID:     testhelper.MkID("unknown"),
+			ExpErr: testhelper.MkExpErr(`unknown severity: "fatal"`),
+			path:   "main.go.begins" + sfxSeverity + "fatal",
+		},
+	}
+
+	for _, tc := range testCases {
+		path, sev, err := trimSeveritySuffix(tc.path)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "path", path, tc.expPath)
+			testhelper.DiffString(t, tc.IDStr(), "severity",
+				string(sev), string(tc.expSev))
+		}
+	}
+}
+
+func TestCheckContentsSeverity(t *testing.T) {
+	const target = "prog/main.go"
+
+	testCases := []struct {
+		testhelper.ID
+		sev              severity
+		warningsAsErrors bool
+		expExitStatus    int
+	}{
+		{
+			ID:            testhelper.MkID("error"),
+			sev:           sevError,
+			expExitStatus: esContent,
+		},
+		{
+			ID:  testhelper.MkID("warning"),
+			sev: sevWarning,
+		},
+		{
+			ID:               testhelper.MkID("warning as error"),
+			sev:              sevWarning,
+			warningsAsErrors: true,
+			expExitStatus:    esContent,
+		},
+		{
+			ID:               testhelper.MkID("info"),
+			sev:              sevInfo,
+			warningsAsErrors: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.warningsAsErrors = tc.warningsAsErrors
+		prog.fileChecks[target] = []fileCheck{
+			{
+				checkType: "contains",
+				severity:  tc.sev,
+				check:     checkContentContains("xyz"),
+			},
+		}
+
+		prog.CheckContents(TemplateFileInfo{target: target}, "package main")
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(),
+			string(tc.sev)+`: "prog/main.go" has unexpected content`+"\n"+
+				"\tdoes not contain:\nxyz\n")
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// strictIgnoreFileName is the name of the file in the target directory
+// giving, in gitignore format, the patterns of those files which are
+// expected to be there even though the template does not produce them.
+const strictIgnoreFileName = ".mkProgDir-ignore"
+
+// strictAllowed lists the names of those entries which may be in the
+// target directory without being produced by the template. These are
+// either made by this program or by the tools it is used with.
+var strictAllowed = []string{
+	".git",
+	journalDirName,
+	strictIgnoreFileName,
+	projectConfigFileName,
+	goModFileName,
+	"go.sum",
+}
+
+// expectedEntries returns the paths, relative to the target directory and
+// slash-separated, of the files, directories and symbolic links produced
+// by the template.
+func (prog *Prog) expectedEntries() (map[string]bool, error) {
+	expected := map[string]bool{}
+
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			tfi, err := prog.getTemplateFileInfo(path, d)
+			if err != nil {
+				return err
+			}
+
+			if tfi.isTheTemplateDir ||
+				tfi.isACheckFile ||
+				tfi.isARequiresFile ||
+				tfi.isAHook {
+				return nil
+			}
+
+			rel, err := filepath.Rel(prog.dir, tfi.target)
+			if err != nil {
+				return err
+			}
+
+			expected[filepath.ToSlash(rel)] = true
+
+			return nil
+		})
+
+	return expected, err
+}
+
+// strictIgnoreRules returns the rules read from the ignore file in the
+// target directory. If there is no such file there are no rules.
+func (prog *Prog) strictIgnoreRules() (*ignoreRules, error) {
+	rules := &ignoreRules{}
+
+	b, err := os.ReadFile( //nolint:gosec
+		filepath.Join(prog.dir, strictIgnoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return rules, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules.add(".", string(b))
+
+	return rules, nil
+}
+
+// findStrayEntries returns the paths of those entries in the target
+// directory which are not produced by the template, are not always
+// allowed and are not matched by the ignore file. A stray directory is
+// reported but its contents are not.
+func (prog *Prog) findStrayEntries() ([]string, error) {
+	expected, err := prog.expectedEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := prog.strictIgnoreRules()
+	if err != nil {
+		return nil, err
+	}
+
+	strays := []string{}
+
+	err = filepath.WalkDir(prog.dir,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(prog.dir, path)
+			if err != nil {
+				return err
+			}
+
+			slashPath := filepath.ToSlash(rel)
+
+			if slashPath == "." || expected[slashPath] {
+				return nil
+			}
+
+			if slices.Contains(strictAllowed, slashPath) ||
+				rules.ignored(slashPath, d.IsDir()) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+
+			strays = append(strays, path)
+
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		})
+
+	return strays, err
+}
+
+// CheckStrayFiles reports any entries in the target directory which are not
+// produced by the template. Entries matched by the patterns in the ignore
+// file in the target directory are not reported.
+func (prog *Prog) CheckStrayFiles() {
+	defer prog.stack.Start("CheckStrayFiles", "Start")()
+
+	intro := prog.stack.Tag()
+
+	strays, err := prog.findStrayEntries()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot check for stray files in %q: %s\n", prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %d\n", intro, "stray entries", len(strays))
+
+	for _, s := range strays {
+		fmt.Fprintf(prog.out, "%q is not produced by the template\n", s)
+	}
+
+	if len(strays) > 0 {
+		fmt.Fprintf(prog.out,
+			"\tadd patterns for any expected files to %q\n",
+			filepath.Join(prog.dir, strictIgnoreFileName))
+		prog.SetExitStatus(esContent)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCheckStrayFiles(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		files         []string
+		ignore        string
+		expOut        string
+		expExitStatus int
+	}{
+		{
+			ID:    testhelper.MkID("no strays"),
+			files: []string{"a.txt", "b/c.txt", "go.mod", ".git/HEAD"},
+		},
+		{
+			ID: testhelper.MkID("project config file"),
+			files: []string{
+				"a.txt", "b/c.txt", projectConfigFileName,
+			},
+		},
+		{
+			ID:    testhelper.MkID("stray file and dir"),
+			files: []string{"a.txt", "b/c.txt", "b/old.txt", "bin/x", "y"},
+			expOut: `"prog/b/old.txt" is not produced by the template` + "\n" +
+				`"prog/bin" is not produced by the template` + "\n" +
+				`"prog/y" is not produced by the template` + "\n" +
+				"\tadd patterns for any expected files to" +
+				` "prog/.mkProgDir-ignore"` + "\n",
+			expExitStatus: esContent,
+		},
+		{
+			ID:     testhelper.MkID("strays ignored"),
+			files:  []string{"a.txt", "b/c.txt", "b/old.txt", "bin/x", "y"},
+			ignore: "# expected files\nbin/\n*.txt\n!y\n",
+			expOut: `"prog/y" is not produced by the template` + "\n" +
+				"\tadd patterns for any expected files to" +
+				` "prog/.mkProgDir-ignore"` + "\n",
+			expExitStatus: esContent,
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt":                   &fstest.MapFile{Data: []byte("a")},
+		"b/c.txt":                 &fstest.MapFile{Data: []byte("c")},
+		"opt.txt" + sfxOptional:   &fstest.MapFile{Data: []byte("o")},
+		"a.txt.begins" + sfxCheck: &fstest.MapFile{Data: []byte("a")},
+		"post-create" + sfxHook:   &fstest.MapFile{Data: []byte("")},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		files := tc.files
+		if tc.ignore != "" {
+			files = append(files, strictIgnoreFileName)
+		}
+
+		for _, name := range files {
+			name = filepath.Join("prog", name)
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				t.Fatalf("cannot make the directory for %q: %s", name, err)
+			}
+
+			err := os.WriteFile(name, []byte(tc.ignore), 0o600)
+			if err != nil {
+				t.Fatalf("cannot write %q: %s", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.name = "prog"
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CheckStrayFiles()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
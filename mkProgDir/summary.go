@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nickwells/english.mod/english"
+)
+
+// taskStats records what was found while checking a single target. It is
+// held in the copy of the Prog made for the task and is copied into the
+// report entry when the task completes.
+type taskStats struct {
+	missing    bool
+	optMissing bool
+	fixed      bool
+	checksRun  int
+}
+
+// checkSummary counts the results of checking the targets (the files,
+// directories and symbolic links given by the template) and the number of
+// failed checks of the target directory as a whole
+type checkSummary struct {
+	checked    int
+	passed     int
+	fixed      int
+	failed     int
+	missing    int
+	optMissing int
+	checksRun  int
+	dirFailed  int
+}
+
+// addEntries adds the checks recorded in the report entries to the
+// summary. A target that is missing is counted as missing rather than as
+// failed and one that has been fixed is counted as fixed rather than as
+// passed.
+func (cs *checkSummary) addEntries(entries []*reportEntry) {
+	cs.checked += len(entries)
+
+	for _, re := range entries {
+		cs.checksRun += re.stats.checksRun
+
+		switch {
+		case re.stats.optMissing:
+			cs.optMissing++
+		case re.stats.missing:
+			cs.missing++
+		case re.stats.fixed:
+			cs.fixed++
+		case re.exitStatus != 0:
+			cs.failed++
+		default:
+			cs.passed++
+		}
+	}
+}
+
+// reportCheckSummary reports the numbers of targets checked, passed, fixed,
+// failed and missing, the number of content checks run and the number of
+// failed checks of the target directory as a whole
+func (prog *Prog) reportCheckSummary() {
+	cs := prog.summary
+
+	fmt.Fprintf(prog.out,
+		"%d %s checked: %d passed, %d fixed, %d failed, %d missing,"+
+			" %d optional missing, %d content %s run,"+
+			" %d directory %s failed\n",
+		cs.checked, english.Plural("target", cs.checked),
+		cs.passed, cs.fixed, cs.failed, cs.missing, cs.optMissing,
+		cs.checksRun, english.Plural("check", cs.checksRun),
+		cs.dirFailed, english.Plural("check", cs.dirFailed))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReportCheckSummary(t *testing.T) {
+	entries := []*reportEntry{
+		{path: "a", stats: taskStats{checksRun: 2}},
+		{path: "b", exitStatus: esContent, stats: taskStats{checksRun: 3}},
+		{path: "c", exitStatus: esMissing, stats: taskStats{missing: true}},
+		{path: "d", stats: taskStats{optMissing: true}},
+		{path: "e", exitStatus: esPerms},
+		{path: "f", stats: taskStats{fixed: true}},
+	}
+
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.out = &out
+
+	prog.summary.dirFailed = 1
+	prog.summary.addEntries(entries)
+
+	prog.reportCheckSummary()
+
+	testhelper.DiffString(t, "summary", "output", out.String(),
+		"6 targets checked: 1 passed, 1 fixed, 2 failed, 1 missing,"+
+			" 1 optional missing, 5 content checks run,"+
+			" 1 directory check failed\n")
+}
+
+func TestCheckContentsAllChecks(t *testing.T) {
+	const target = "prog/main.go"
+
+	testCases := []struct {
+		testhelper.ID
+		failFast     bool
+		expOut       string
+		expChecksRun int
+	}{
+		{
+			ID: testhelper.MkID("all checks"),
+			expOut: `error: "prog/main.go" has unexpected content` + "\n" +
+				"\tdoes not contain:\nxyz\n" +
+				`error: "prog/main.go" has unexpected content` + "\n" +
+				"\tdoes not contain:\nabc\n",
+			expChecksRun: 3,
+		},
+		{
+			ID:       testhelper.MkID("fail fast"),
+			failFast: true,
+			expOut: `error: "prog/main.go" has unexpected content` + "\n" +
+				"\tdoes not contain:\nxyz\n",
+			expChecksRun: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.failFast = tc.failFast
+		prog.fileChecks[target] = []fileCheck{
+			{
+				checkType: "contains",
+				severity:  sevError,
+				check:     checkContentContains("xyz"),
+			},
+			{
+				checkType: "contains",
+				severity:  sevError,
+				check:     checkContentContains("main"),
+			},
+			{
+				checkType: "contains",
+				severity:  sevError,
+				check:     checkContentContains("abc"),
+			},
+		}
+
+		prog.CheckContents(TemplateFileInfo{target: target}, "package main")
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esContent)
+		testhelper.DiffInt(t, tc.IDStr(), "checks run",
+			prog.stats.checksRun, tc.expChecksRun)
+	}
+}
+
+func TestCheckTargetDirQuiet(t *testing.T) {
+	const summary = "1 target checked: 0 passed, 0 fixed, 1 failed," +
+		" 0 missing," +
+		" 0 optional missing, 1 content check run," +
+		" 1 directory check failed\n"
+
+	testCases := []struct {
+		testhelper.ID
+		quiet bool
+	}{
+		{
+			ID: testhelper.MkID("not quiet"),
+		},
+		{
+			ID:    testhelper.MkID("quiet"),
+			quiet: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		for name, contents := range map[string]string{
+			"a.txt": "x",
+			"stray": "",
+		} {
+			name = filepath.Join("prog", name)
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				t.Fatalf("cannot make the directory for %q: %s", name, err)
+			}
+
+			if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+				t.Fatalf("cannot write %q: %s", name, err)
+			}
+		}
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.action = aCheck
+		prog.quiet = tc.quiet
+		prog.strict = true
+		prog.dir = "prog"
+		prog.name = "prog"
+		prog.templateFS = fstest.MapFS{
+			"a.txt":                   &fstest.MapFile{Data: []byte("a")},
+			"a.txt.begins" + sfxCheck: &fstest.MapFile{Data: []byte("a")},
+		}
+		prog.walkerBase = "."
+		prog.addAllMacros()
+
+		prog.CheckTargetDir()
+
+		if tc.quiet {
+			testhelper.DiffString(t, tc.IDStr(), "output", out.String(),
+				summary)
+		} else if !strings.HasSuffix(out.String(), summary) ||
+			out.String() == summary {
+			t.Log(tc.IDStr())
+			t.Errorf("	: expected the checks then the summary, got: %q",
+				out.String())
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esContent)
+	}
+}
+
+func TestCheckTargetDirFix(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := os.Mkdir("prog", 0o755); err != nil {
+		t.Fatalf("cannot make the program directory: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join("prog", "a.txt"), []byte("a"),
+		0o664); err != nil {
+		t.Fatalf("cannot write a.txt: %s", err)
+	}
+
+	var out bytes.Buffer
+
+	prog := NewProg()
+	prog.out = &out
+	prog.action = aFix
+	prog.dir = "prog"
+	prog.name = "prog"
+	prog.templateFS = fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+		"b.txt": &fstest.MapFile{Data: []byte("b")},
+		"c":     &fstest.MapFile{Mode: fs.ModeDir | 0o775},
+	}
+	prog.walkerBase = "."
+	prog.addAllMacros()
+
+	prog.CheckTargetDir()
+
+	testhelper.DiffString(t, "fix", "output", out.String(),
+		`"prog/b.txt" did not exist, it has been created`+"\n"+
+			`directory "prog/c" does not exist`+"\n"+
+			"3 targets checked: 1 passed, 1 fixed, 0 failed,"+
+			" 1 missing, 0 optional missing, 0 content checks run,"+
+			" 0 directory checks failed\n")
+	testhelper.DiffInt(t, "fix", "exit status", prog.exitStatus, esMissing)
+	testhelper.DiffString(t, "fix", "fixed file",
+		progFiles(t, "prog")["b.txt"], "b")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// suppressDirective is the text which introduces a directive in a target
+// file to suppress some of the content checks on that file
+const suppressDirective = "mkProgDir:ignore"
+
+var (
+	// suppressStartRE matches the start of a suppression directive, the
+	// directive must be followed by white space or the end of the line
+	suppressStartRE = regexp.MustCompile(
+		regexp.QuoteMeta(suppressDirective) + `(\s|$)`)
+
+	// suppressRE matches a suppression directive. The first sub-match is
+	// the comma-separated list of check types and the second is the
+	// (optional) reason.
+	suppressRE = regexp.MustCompile(
+		regexp.QuoteMeta(suppressDirective) +
+			`\s+([A-Za-z]+(?:,[A-Za-z]+)*)` +
+			`(?:\s+reason="([^"]*)")?`)
+)
+
+// fileCheck records a content check together with its check type and
+// severity
+type fileCheck struct {
+	checkType string
+	severity  severity
+	check     checkContentFunc
+}
+
+// suppression records a directive in a target file suppressing the content
+// checks of the given types
+type suppression struct {
+	line       int
+	checkTypes []string
+	reason     string
+	used       map[string]bool
+}
+
+// reasonOrDflt returns the reason for the suppression or a default value if
+// no reason was given
+func (sup suppression) reasonOrDflt() string {
+	if strings.TrimSpace(sup.reason) == "" {
+		return "no reason given"
+	}
+
+	return sup.reason
+}
+
+// checkTypeName returns the name of the check type as given in a
+// suppression directive, this is the check type suffix without the
+// leading '.'
+func checkTypeName(suffix string) string {
+	return strings.TrimPrefix(suffix, ".")
+}
+
+// parseSuppressions finds the suppression directives in the contents. It
+// returns the suppressions together with a description of any problems
+// with the directives.
+func parseSuppressions(contents string) ([]*suppression, []string) {
+	sups := []*suppression{}
+	problems := []string{}
+
+	for i, line := range strings.Split(contents, "\n") {
+		if !suppressStartRE.MatchString(line) {
+			continue
+		}
+
+		lineNum := i + 1
+
+		m := suppressRE.FindStringSubmatchIndex(line)
+		if m == nil {
+			problems = append(problems,
+				fmt.Sprintf("line %d: the %s directive has no check types",
+					lineNum, suppressDirective))
+
+			continue
+		}
+
+		sup := &suppression{
+			line: lineNum,
+			used: map[string]bool{},
+		}
+
+		if m[4] >= 0 {
+			sup.reason = line[m[4]:m[5]]
+		}
+
+		for ct := range strings.SplitSeq(line[m[2]:m[3]], ",") {
+			if _, ok := checkTypeMap["."+ct]; !ok {
+				problems = append(problems,
+					fmt.Sprintf("line %d: unknown check type: %q",
+						lineNum, ct))
+
+				continue
+			}
+
+			sup.checkTypes = append(sup.checkTypes, ct)
+		}
+
+		if strings.TrimSpace(sup.reason) == "" {
+			problems = append(problems,
+				fmt.Sprintf("line %d: the %s directive gives no reason",
+					lineNum, suppressDirective))
+		}
+
+		sups = append(sups, sup)
+	}
+
+	return sups, problems
+}
+
+// suppressedBy returns the first suppression which suppresses checks of
+// the given type or nil if there is none
+func suppressedBy(sups []*suppression, checkType string) *suppression {
+	for _, sup := range sups {
+		if slices.Contains(sup.checkTypes, checkType) {
+			return sup
+		}
+	}
+
+	return nil
+}
+
+// unusedSuppressions returns a description of each check type in the
+// suppressions which has been checked but has not suppressed a failed
+// check
+func unusedSuppressions(sups []*suppression, checked map[string]bool,
+) []string {
+	unused := []string{}
+
+	for _, sup := range sups {
+		for _, ct := range sup.checkTypes {
+			if !checked[ct] || sup.used[ct] {
+				continue
+			}
+
+			unused = append(unused,
+				fmt.Sprintf("line %d: the %s directive for %q checks"+
+					" no longer suppresses anything",
+					sup.line, suppressDirective, ct))
+		}
+	}
+
+	return unused
+}
+
+// reportSuppressed writes a description of the suppressed checks
+func reportSuppressed(w io.Writer, path string, suppressed []string) {
+	if len(suppressed) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%q has suppressed checks:\n", path)
+
+	for _, s := range suppressed {
+		fmt.Fprintf(w, "\t%s\n", s)
+	}
+}
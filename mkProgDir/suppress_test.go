@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		contents      string
+		expLines      []int
+		expCheckTypes [][]string
+		expReasons    []string
+		expProblems   []string
+	}{
+		{
+			ID:          testhelper.MkID("none"),
+			contents:    "package main\n",
+			expProblems: []string{},
+		},
+		{
+			ID: testhelper.MkID("good"),
+			contents: "package main\n" +
+				`// mkProgDir:ignore begins,contains reason="custom"` + "\n",
+			expLines:      []int{2},
+			expCheckTypes: [][]string{{"begins", "contains"}},
+			expReasons:    []string{"custom"},
+			expProblems:   []string{},
+		},
+		{
+			ID: testhelper.MkID("not a directive"),
+			contents: `const d = "mkProgDir:ignore"` + "\n" +
+				`# mkProgDir:ignore matches reason="other"` + "\n",
+			expLines:      []int{2},
+			expCheckTypes: [][]string{{"matches"}},
+			expReasons:    []string{"other"},
+			expProblems:   []string{},
+		},
+		{
+			ID: testhelper.MkID("bad"),
+			contents: "// mkProgDir:ignore\n" +
+				"// mkProgDir:ignore ends,nonesuch\n",
+			expLines:      []int{2},
+			expCheckTypes: [][]string{{"ends"}},
+			expReasons:    []string{""},
+			expProblems: []string{
+				"line 1: the mkProgDir:ignore directive has no check types",
+				`line 2: unknown check type: "nonesuch"`,
+				"line 2: the mkProgDir:ignore directive gives no reason",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		sups, problems := parseSuppressions(tc.contents)
+
+		lines := []int{}
+		checkTypes := [][]string{}
+		reasons := []string{}
+
+		for _, sup := range sups {
+			lines = append(lines, sup.line)
+			checkTypes = append(checkTypes, sup.checkTypes)
+			reasons = append(reasons, sup.reason)
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "problems",
+			problems, tc.expProblems)
+
+		if len(tc.expLines) == 0 {
+			testhelper.DiffInt(t, tc.IDStr(), "suppressions", len(sups), 0)
+			continue
+		}
+
+		if err := testhelper.DiffVals(lines, tc.expLines); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: lines: %s\n", err)
+		}
+
+		err := testhelper.DiffVals(checkTypes, tc.expCheckTypes)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: check types: %s\n", err)
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "reasons",
+			reasons, tc.expReasons)
+	}
+}
+
+func TestCheckContentsSuppressed(t *testing.T) {
+	const target = "prog/main.go"
+
+	testCases := []struct {
+		testhelper.ID
+		contents      string
+		expOut        string
+		expExitStatus int
+	}{
+		{
+			ID:       testhelper.MkID("passes"),
+			contents: "package main\n",
+		},
+		{
+			ID:       testhelper.MkID("fails"),
+			contents: "// custom\npackage main\n",
+			expOut: `error: "prog/main.go" has unexpected content` + "\n" +
+				"\tit should start with:\npackage main\n" +
+				"\tactually starts with:\n// custom\npackage main\n\n",
+			expExitStatus: esContent,
+		},
+		{
+			ID: testhelper.MkID("suppressed"),
+			contents: "// custom\npackage main\n" +
+				`// mkProgDir:ignore begins reason="custom header"` + "\n",
+			expOut: `"prog/main.go" has suppressed checks:` + "\n" +
+				"\tbegins (line 3): custom header\n",
+		},
+		{
+			ID: testhelper.MkID("suppression unused"),
+			contents: "package main\n" +
+				`// mkProgDir:ignore begins reason="custom header"` + "\n",
+			expOut: `"prog/main.go" : line 2: the mkProgDir:ignore` +
+				` directive for "begins" checks no longer suppresses` +
+				" anything\n",
+			expExitStatus: esContent,
+		},
+		{
+			ID: testhelper.MkID("suppression without a reason"),
+			contents: "// custom\npackage main\n" +
+				"// mkProgDir:ignore begins\n",
+			expOut: `"prog/main.go" has suppressed checks:` + "\n" +
+				"\tbegins (line 3): no reason given\n" +
+				`"prog/main.go" : line 3: the mkProgDir:ignore` +
+				" directive gives no reason\n",
+			expExitStatus: esContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.fileChecks[target] = []fileCheck{
+			{
+				checkType: "begins",
+				severity:  sevError,
+				check:     checkContentBegins("package main"),
+			},
+		}
+
+		prog.CheckContents(TemplateFileInfo{target: target}, tc.contents)
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
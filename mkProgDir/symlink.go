@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// CreateTargetSymlink creates the target symbolic link, pointing at the
+// link target given in the template.
+func (prog *Prog) CreateTargetSymlink(tfi TemplateFileInfo) error {
+	err := symlinkAtomic(tfi.linkTarget, tfi.target)
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Can't create the symbolic link %q: %s\n", tfi.target, err)
+		prog.SetExitStatus(esIOError)
+	}
+
+	return err
+}
+
+// fixSymlink replaces the target with a symbolic link pointing at the link
+// target given in the template. It will not replace a directory.
+func (prog *Prog) fixSymlink(tfi TemplateFileInfo, fi fs.FileInfo) {
+	if fi.IsDir() {
+		fmt.Fprintf(prog.out,
+			"Can't replace the directory %q with a symbolic link\n",
+			tfi.target)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	before := prog.journalBefore(tfi.target)
+
+	if err := symlinkAtomic(tfi.linkTarget, tfi.target); err != nil {
+		fmt.Fprintf(prog.out,
+			"Can't replace %q with a symbolic link: %s\n", tfi.target, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	prog.journalAfter(tfi.target, before)
+
+	prog.stats.fixed = true
+
+	fmt.Fprintf(prog.out,
+		"%q has been replaced with a symbolic link to %q\n",
+		tfi.target, tfi.linkTarget)
+}
+
+// CheckSymlink checks that the given target exists, is a symbolic link and
+// points to the expected link target. If the action is aFix then a missing
+// or incorrect link will be replaced.
+func (prog *Prog) CheckSymlink(tfi TemplateFileInfo) {
+	path := tfi.target
+	defer prog.stack.Start("CheckSymlink",
+		fmt.Sprintf("Start%25s: %q", "link to check", path))()
+
+	intro := prog.stack.Tag()
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		prog.ReportStatErr(tfi, err)
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "link exists")
+
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		if prog.action == aFix {
+			prog.fixSymlink(tfi, fi)
+			return
+		}
+
+		fmt.Fprintf(prog.out, "%q is not a symbolic link\n", path)
+		prog.SetExitStatus(esMissing)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "is a symbolic link")
+
+	linkTarget, err := os.Readlink(path)
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Symbolic link: %q can't be read: %s\n", path, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if linkTarget != tfi.linkTarget {
+		if prog.action == aFix {
+			prog.fixSymlink(tfi, fi)
+			return
+		}
+
+		fmt.Fprintf(prog.out, "%q points to the wrong place\n", path)
+		fmt.Fprintf(prog.out, "\texpected link target %q\n", tfi.linkTarget)
+		fmt.Fprintf(prog.out, "\t  actual link target %q\n", linkTarget)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "points to the link target")
+
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(prog.out,
+			"%q is a symbolic link to %q which cannot be reached: %s\n",
+			path, linkTarget, err)
+		prog.SetExitStatus(esContent)
+
+		return
+	}
+
+	prog.verbosef("%s %30s: %s\n", intro, "", "link target exists")
+}
@@ -4,7 +4,9 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/nickwells/location.mod/location"
@@ -31,6 +33,10 @@ const (
 	sfxGenerate = "--mkProgDir-Generate"
 	sfxCheck    = "--mkProgDir-Check"
 	sfxOptional = "--mkProgDir-Optional"
+	sfxRequires = "--mkProgDir-Requires"
+	sfxPerms    = "--mkProgDir-Perms-"
+	sfxSymlink  = "--mkProgDir-Symlink"
+	sfxHook     = "--mkProgDir-Hook"
 )
 
 // TemplateFileInfo contains the information about a template file
@@ -39,19 +45,84 @@ type TemplateFileInfo struct {
 	target string
 
 	contents string
+	perms    fs.FileMode
 
 	isADir           bool
 	isTheTemplateDir bool
 	isAGenFile       bool
 	isACheckFile     bool
 	isAnOptionalFile bool
+	isARequiresFile  bool
+	isASymlink       bool
+	isAHook          bool
+
+	linkTarget string
 
 	checkTypeSuffix string
+	severity        severity
 }
 
 // dot followed by one or more digits at the end of the string
 var idRE = regexp.MustCompile(`\.\d+$`)
 
+// the permissions suffix followed by the rest of the file name
+var permsRE = regexp.MustCompile(sfxPerms + `([^/]*)$`)
+
+// three octal digits, optionally with a leading zero. The setuid, setgid and
+// sticky bits are not allowed as Go does not hold them in the permission
+// bits of a FileMode.
+var permsValRE = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+// trimPermsSuffix strips the trailing permissions suffix if present and
+// returns the stripped path, the permissions and true. If there is no
+// permissions suffix it returns the path unchanged, zero permissions and
+// false. An error is returned if the permissions suffix is not followed by
+// valid permissions.
+func trimPermsSuffix(path string) (string, fs.FileMode, bool, error) {
+	m := permsRE.FindStringSubmatch(path)
+	if m == nil {
+		return path, 0, false, nil
+	}
+
+	if !permsValRE.MatchString(m[1]) {
+		return "", 0, false, fmt.Errorf(
+			"bad permissions: %q, they should be three octal digits"+
+				" optionally preceded by a '0'", m[1])
+	}
+
+	perms, err := strconv.ParseUint(m[1], 8, 32)
+	if err != nil { // can't happen - the regexp only matches octal digits
+		panic(fmt.Errorf("bad permissions suffix %q: %w", m[0], err))
+	}
+
+	return strings.TrimSuffix(path, m[0]), fs.FileMode(perms), true, nil
+}
+
+// setDefaultPerms sets the permissions of the target to be the default
+// value. This is the value given by the permissions parameter unless the
+// template is on disk and the parameter has not been given in which case
+// it is the permissions of the template file itself.
+func (prog Prog) setDefaultPerms(tfi *TemplateFileInfo, d fs.DirEntry,
+) error {
+	tfi.perms = prog.filePerms
+	if tfi.isADir {
+		tfi.perms = prog.dirPerms
+	}
+
+	if !prog.templateOnDisk || prog.filePermsGiven {
+		return nil
+	}
+
+	fi, err := d.Info()
+	if err != nil {
+		return fmt.Errorf("can't get the permissions of %q: %w", tfi.path, err)
+	}
+
+	tfi.perms = fi.Mode().Perm()
+
+	return nil
+}
+
 // trimNumSuffix strips the trailing numeric suffix if present and returns
 // the stripped path
 func trimNumSuffix(path string) string {
@@ -96,6 +167,22 @@ func (prog Prog) getTFIContent(tfi *TemplateFileInfo) error {
 	return nil
 }
 
+// getTFILinkTarget sets the link target from the template file which
+// should be a symbolic link. The template file system must support the
+// reading of symbolic links.
+func (prog Prog) getTFILinkTarget(tfi *TemplateFileInfo) error {
+	linkTarget, err := fs.ReadLink(prog.templateFS, tfi.path)
+	if err != nil {
+		return fmt.Errorf("can't read the template symbolic link %q: %w",
+			tfi.path, err)
+	}
+
+	tfi.isASymlink = true
+	tfi.linkTarget = filepath.FromSlash(linkTarget)
+
+	return nil
+}
+
 // getCheckTypeSuffix sets the tfi.checkTypeSuffix or else returns an error
 // indicating that no valid suffix could be found.
 func getCheckTypeSuffix(tfi *TemplateFileInfo, path string) error {
@@ -117,12 +204,28 @@ func (prog Prog) getTemplateFileInfo(path string, d fs.DirEntry,
 		isADir:     d.IsDir(),
 		isAGenFile: strings.HasSuffix(path, sfxGenerate),
 	}
+	err := prog.setDefaultPerms(&tfi, d)
+	if err != nil {
+		return TemplateFileInfo{}, err
+	}
+
 	if tfi.isADir {
 		prog.populateTFIDirInfo(&tfi)
 		return tfi, nil
 	}
 
-	err := prog.getTFIContent(&tfi)
+	if d.Type()&fs.ModeSymlink != 0 {
+		err = prog.getTFILinkTarget(&tfi)
+		if err != nil {
+			return TemplateFileInfo{}, err
+		}
+
+		tfi.target = prog.makeNewPath(path)
+
+		return tfi, nil
+	}
+
+	err = prog.getTFIContent(&tfi)
 	if err != nil {
 		return TemplateFileInfo{}, err
 	}
@@ -131,6 +234,37 @@ func (prog Prog) getTemplateFileInfo(path string, d fs.DirEntry,
 		path = strings.TrimSuffix(path, sfxGenerate)
 	}
 
+	p, perms, ok, err := trimPermsSuffix(path)
+	if err != nil {
+		return TemplateFileInfo{}, fmt.Errorf("%q : %w", tfi.path, err)
+	}
+
+	if ok {
+		tfi.perms = perms
+		path = p
+	}
+
+	if strings.HasSuffix(path, sfxSymlink) {
+		tfi.isASymlink = true
+		path = strings.TrimSuffix(path, sfxSymlink)
+
+		tfi.linkTarget = filepath.FromSlash(strings.TrimSpace(tfi.contents))
+		if tfi.linkTarget == "" {
+			return TemplateFileInfo{},
+				fmt.Errorf("%q : the symbolic link target is empty", tfi.path)
+		}
+	}
+
+	if strings.HasSuffix(path, sfxHook) {
+		tfi.isAHook = true
+		path = strings.TrimSuffix(path, sfxHook)
+	}
+
+	if strings.HasSuffix(path, sfxRequires) {
+		tfi.isARequiresFile = true
+		path = strings.TrimSuffix(path, sfxRequires)
+	}
+
 	if strings.HasSuffix(path, sfxOptional) {
 		tfi.isAnOptionalFile = true
 		path = strings.TrimSuffix(path, sfxOptional)
@@ -139,9 +273,17 @@ func (prog Prog) getTemplateFileInfo(path string, d fs.DirEntry,
 	if strings.HasSuffix(path, sfxCheck) {
 		tfi.isACheckFile = true
 		path = strings.TrimSuffix(path, sfxCheck)
+
+		var err error
+
+		path, tfi.severity, err = trimSeveritySuffix(path)
+		if err != nil {
+			return TemplateFileInfo{}, fmt.Errorf("%q : %w", tfi.path, err)
+		}
+
 		path = trimNumSuffix(path)
 
-		err := getCheckTypeSuffix(&tfi, path)
+		err = getCheckTypeSuffix(&tfi, path)
 		if err != nil {
 			return TemplateFileInfo{}, err
 		}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTrimPermsSuffix(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		path     string
+		expPath  string
+		expPerms fs.FileMode
+		expOK    bool
+	}{
+		{
+			ID:      testhelper.MkID("no suffix"),
+			path:    "build.sh",
+			expPath: "build.sh",
+		},
+		{
+			ID:       testhelper.MkID("three digits"),
+			path:     "build.sh" + sfxPerms + "755",
+			expPath:  "build.sh",
+			expPerms: 0o755,
+			expOK:    true,
+		},
+		{
+			ID:       testhelper.MkID("four digits"),
+			path:     "dir/ro.txt" + sfxPerms + "0444",
+			expPath:  "dir/ro.txt",
+			expPerms: 0o444,
+			expOK:    true,
+		},
+		{
+			ID:      testhelper.MkID("suffix in a directory name"),
+			path:    "bin" + sfxPerms + "0755/build.sh",
+			expPath: "bin" + sfxPerms + "0755/build.sh",
+		},
+		{
+			ID:     testhelper.MkID("setuid bit"),
+			ExpErr: testhelper.MkExpErr(`bad permissions: "4755"`),
+			path:   "build.sh" + sfxPerms + "4755",
+		},
+		{
+			ID:     testhelper.MkID("non-octal digits"),
+			ExpErr: testhelper.MkExpErr(`bad permissions: "0789"`),
+			path:   "build.sh" + sfxPerms + "0789",
+		},
+		{
+			ID:     testhelper.MkID("suffix not at the end"),
+			ExpErr: testhelper.MkExpErr(`bad permissions: "0755.txt"`),
+			path:   "build.sh" + sfxPerms + "0755.txt",
+		},
+	}
+
+	for _, tc := range testCases {
+		path, perms, ok, err := trimPermsSuffix(tc.path)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "path", path, tc.expPath)
+			testhelper.DiffInt(t, tc.IDStr(), "perms", perms, tc.expPerms)
+			testhelper.DiffBool(t, tc.IDStr(), "ok", ok, tc.expOK)
+		}
+	}
+}
+
+func TestGetTemplateFileInfo(t *testing.T) {
+	const progDir = "dir/prog"
+
+	tmplFS := fstest.MapFS{
+		"plain.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"gen.txt" + sfxGenerate: &fstest.MapFile{
+			Data: []byte("${" + macroProgName + "}"),
+		},
+		"build.sh" + sfxPerms + "0755": &fstest.MapFile{
+			Data: []byte("#!/bin/sh\n"),
+		},
+		"run.sh" + sfxPerms + "4755": &fstest.MapFile{
+			Data: []byte("#!/bin/sh\n"),
+		},
+		"LICENSE" + sfxSymlink: &fstest.MapFile{
+			Data: []byte("../LICENSE\n"),
+		},
+		"link" + sfxSymlink + sfxGenerate: &fstest.MapFile{
+			Data: []byte("../${" + macroProgName + "}.txt"),
+		},
+		"empty" + sfxSymlink: &fstest.MapFile{Data: []byte(" \n")},
+		"disk-link": &fstest.MapFile{
+			Data: []byte("../shared"),
+			Mode: fs.ModeSymlink,
+		},
+		"main.go.begins.1" + sfxCheck: &fstest.MapFile{
+			Data: []byte("package main"),
+		},
+		"main.go.bad" + sfxCheck: &fstest.MapFile{},
+		"main.go.ends" + sfxSeverity + "warning" + sfxCheck: &fstest.MapFile{
+			Data: []byte("}\n"),
+		},
+		"main.go.ends" + sfxSeverity + "bad" + sfxCheck: &fstest.MapFile{},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		path   string
+		expTFI TemplateFileInfo
+	}{
+		{
+			ID:   testhelper.MkID("plain file"),
+			path: "plain.go",
+			expTFI: TemplateFileInfo{
+				target:   filepath.Join(progDir, "plain.go"),
+				contents: "package main\n",
+				perms:    0o664,
+			},
+		},
+		{
+			ID:   testhelper.MkID("generated file"),
+			path: "gen.txt" + sfxGenerate,
+			expTFI: TemplateFileInfo{
+				target:     filepath.Join(progDir, "gen.txt"),
+				contents:   "prog",
+				perms:      0o664,
+				isAGenFile: true,
+			},
+		},
+		{
+			ID:   testhelper.MkID("file with permissions"),
+			path: "build.sh" + sfxPerms + "0755",
+			expTFI: TemplateFileInfo{
+				target:   filepath.Join(progDir, "build.sh"),
+				contents: "#!/bin/sh\n",
+				perms:    0o755,
+			},
+		},
+		{
+			ID:   testhelper.MkID("symlink"),
+			path: "LICENSE" + sfxSymlink,
+			expTFI: TemplateFileInfo{
+				target:     filepath.Join(progDir, "LICENSE"),
+				contents:   "../LICENSE\n",
+				perms:      0o664,
+				isASymlink: true,
+				linkTarget: filepath.FromSlash("../LICENSE"),
+			},
+		},
+		{
+			ID:   testhelper.MkID("generated symlink"),
+			path: "link" + sfxSymlink + sfxGenerate,
+			expTFI: TemplateFileInfo{
+				target:     filepath.Join(progDir, "link"),
+				contents:   "../prog.txt",
+				perms:      0o664,
+				isAGenFile: true,
+				isASymlink: true,
+				linkTarget: filepath.FromSlash("../prog.txt"),
+			},
+		},
+		{
+			ID:     testhelper.MkID("symlink with no link target"),
+			ExpErr: testhelper.MkExpErr("the symbolic link target is empty"),
+			path:   "empty" + sfxSymlink,
+		},
+		{
+			ID:   testhelper.MkID("symlink in the template"),
+			path: "disk-link",
+			expTFI: TemplateFileInfo{
+				target:     filepath.Join(progDir, "disk-link"),
+				perms:      0o664,
+				isASymlink: true,
+				linkTarget: filepath.FromSlash("../shared"),
+			},
+		},
+		{
+			ID:   testhelper.MkID("check file"),
+			path: "main.go.begins.1" + sfxCheck,
+			expTFI: TemplateFileInfo{
+				target:          filepath.Join(progDir, "main.go"),
+				contents:        "package main",
+				perms:           0o664,
+				isACheckFile:    true,
+				checkTypeSuffix: beginsSuffix,
+				severity:        sevError,
+			},
+		},
+		{
+			ID:   testhelper.MkID("check file with a severity"),
+			path: "main.go.ends" + sfxSeverity + "warning" + sfxCheck,
+			expTFI: TemplateFileInfo{
+				target:          filepath.Join(progDir, "main.go"),
+				contents:        "}\n",
+				perms:           0o664,
+				isACheckFile:    true,
+				checkTypeSuffix: endsSuffix,
+				severity:        sevWarning,
+			},
+		},
+		{
+			ID:     testhelper.MkID("file with bad permissions"),
+			ExpErr: testhelper.MkExpErr(`bad permissions: "4755"`),
+			path:   "run.sh" + sfxPerms + "4755",
+		},
+		{
+			ID:     testhelper.MkID("check file with a bad severity"),
+			ExpErr: testhelper.MkExpErr(`unknown severity: "bad"`),
+			path:   "main.go.ends" + sfxSeverity + "bad" + sfxCheck,
+		},
+		{
+			ID:     testhelper.MkID("check file with a bad check-type"),
+			ExpErr: testhelper.MkExpErr("has no valid check-type suffix"),
+			path:   "main.go.bad" + sfxCheck,
+		},
+	}
+
+	prog := NewProg()
+	prog.dir = progDir
+	prog.name = filepath.Base(progDir)
+	prog.templateFS = tmplFS
+	prog.walkerBase = "."
+	prog.addAllMacros()
+
+	for _, tc := range testCases {
+		fi, err := fs.Lstat(tmplFS, tc.path)
+		if err != nil {
+			t.Fatalf("cannot stat the template file %q: %s", tc.path, err)
+		}
+
+		tfi, err := prog.getTemplateFileInfo(tc.path,
+			fs.FileInfoToDirEntry(fi))
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			tc.expTFI.path = tc.path
+
+			err = testhelper.DiffVals(tfi, tc.expTFI)
+			if err != nil {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: %s\n", err)
+			}
+		}
+	}
+}
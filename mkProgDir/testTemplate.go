@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// sampleProgName is the program name used when testing a template if no
+// program name has been given
+const sampleProgName = "sampleProg"
+
+// sampleMacroPfx is the prefix of the placeholder value given, when testing
+// a template, to any macro which has no value
+const sampleMacroPfx = "sample"
+
+// sampleMacroDefs returns macro definitions giving a placeholder value to
+// each macro referenced by the template which has no value. The available
+// macros are always given a value and so are not included.
+func (prog *Prog) sampleMacroDefs() ([]string, error) {
+	tms, err := prog.templateMacros()
+	if err != nil {
+		return nil, err
+	}
+
+	defs := []string{}
+
+	for _, tm := range tms {
+		if tm.isAvailable() {
+			continue
+		}
+
+		if _, ok := prog.macroValue(tm.name); ok {
+			continue
+		}
+
+		defs = append(defs, tm.name+macroDefSep+sampleMacroPfx+tm.name)
+	}
+
+	return defs, nil
+}
+
+// TestTemplate creates a program directory from the template in a
+// temporary directory and then checks it against the template, building
+// it as well if the build is to be checked. Any macro used by the template
+// which has no value is given a placeholder value. Any check that fails on
+// the freshly created directory is reported. The temporary directory is
+// removed afterwards.
+func (prog *Prog) TestTemplate() {
+	defer prog.stack.Start("TestTemplate", "Start")()
+
+	intro := prog.stack.Tag()
+
+	tmpDir, err := os.MkdirTemp("", "mkProgDir-test-template-")
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot create the temporary directory: %s\n",
+			err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			fmt.Fprintf(prog.out,
+				"Cannot remove the temporary directory (%q): %s\n",
+				tmpDir, err)
+		}
+	}()
+
+	name := prog.name
+	if name == "" {
+		name = sampleProgName
+	}
+
+	dir := filepath.Join(tmpDir, name)
+
+	verbose.Printf("%s %30s: %q\n", intro, "test directory", dir)
+
+	defs, err := prog.sampleMacroDefs()
+	if err != nil {
+		fmt.Fprintf(prog.out,
+			"Cannot find the macros used by the template: %s\n", err)
+		prog.SetExitStatus(esTemplate)
+
+		return
+	}
+
+	tp := *prog
+	tp.macroDefs = append(slices.Clone(prog.macroDefs), defs...)
+
+	for _, def := range defs {
+		verbose.Printf("%s %30s: %s\n", intro, "placeholder macro", def)
+	}
+
+	cp, err := tp.forTarget(dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot test the template: %s\n", err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	cp.action = aCreate
+	cp.CreateTargetDir()
+
+	if cp.exitStatus != 0 {
+		fmt.Fprintln(prog.out,
+			"The program directory could not be created from the template")
+		prog.SetExitStatus(cp.exitStatus)
+
+		return
+	}
+
+	verbose.Printf("%s %30s: %s\n", intro, "", "test directory created")
+
+	cp, err = tp.forTarget(dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot test the template: %s\n", err)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	cp.action = aCheck
+	cp.reportAllFiles = true
+	cp.CheckTargetDir()
+
+	if cp.exitStatus != 0 {
+		fmt.Fprintln(prog.out,
+			"The program directory created from the template"+
+				" fails the template's own checks")
+		prog.SetExitStatus(esTemplate)
+
+		return
+	}
+
+	verbose.Printf("%s %30s: %s\n", intro, "", "all checks passed")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTestTemplate(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		tmplFS        fstest.MapFS
+		macroDefs     []string
+		expOutPart    string
+		expExitStatus int
+	}{
+		{
+			ID: testhelper.MkID("checks pass"),
+			tmplFS: fstest.MapFS{
+				"a.txt" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("${" + macroProgName + "}\n"),
+				},
+				"a.txt.begins" + sfxCheck: &fstest.MapFile{
+					Data: []byte(sampleProgName),
+				},
+				"b.txt" + sfxOptional: &fstest.MapFile{},
+			},
+		},
+		{
+			ID: testhelper.MkID("checks fail"),
+			tmplFS: fstest.MapFS{
+				"a.txt": &fstest.MapFile{Data: []byte("hello\n")},
+				"a.txt.begins" + sfxCheck: &fstest.MapFile{
+					Data: []byte("bye"),
+				},
+			},
+			expOutPart:    "fails the template's own checks",
+			expExitStatus: esTemplate,
+		},
+		{
+			ID: testhelper.MkID("user macro given a placeholder"),
+			tmplFS: fstest.MapFS{
+				"a.txt" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("${Author}\n"),
+				},
+				"a.txt.begins" + sfxCheck: &fstest.MapFile{
+					Data: []byte(sampleMacroPfx + "Author"),
+				},
+			},
+		},
+		{
+			ID: testhelper.MkID("user macro with a value"),
+			tmplFS: fstest.MapFS{
+				"a.txt" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("${Author}\n"),
+				},
+				"a.txt.begins" + sfxCheck: &fstest.MapFile{
+					Data: []byte("Jo"),
+				},
+			},
+			macroDefs: []string{"Author=Jo"},
+		},
+		{
+			ID: testhelper.MkID("bad macro"),
+			tmplFS: fstest.MapFS{
+				"a.txt" + sfxGenerate: &fstest.MapFile{
+					Data: []byte("${Undefined\n"),
+				},
+			},
+			expOutPart:    "Cannot find the macros used by the template",
+			expExitStatus: esTemplate,
+		},
+		{
+			ID: testhelper.MkID("create fails"),
+			tmplFS: fstest.MapFS{
+				"a.txt.bad" + sfxCheck: &fstest.MapFile{
+					Data: []byte("a"),
+				},
+			},
+			expOutPart:    "could not be created from the template",
+			expExitStatus: esTemplate,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.action = aTestTemplate
+		prog.templateFS = tc.tmplFS
+		prog.walkerBase = "."
+		prog.macroDefs = tc.macroDefs
+		prog.addAllMacros()
+
+		prog.TestTemplate()
+
+		if !strings.Contains(out.String(), tc.expOutPart) {
+			t.Log(tc.IDStr())
+			t.Logf("\t: expected output to contain: %q\n", tc.expOutPart)
+			t.Errorf("\t:                  actually: %q\n", out.String())
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, tc.expExitStatus)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/macros.mod/macros"
+)
+
+const (
+	progDirMainFile   = "main.go"
+	progDirMainMarker = "makeParamSet"
+)
+
+// isAProgDir returns true if the directory contains a main.go file which
+// refers to makeParamSet, this being taken as a sign that the directory
+// holds a program created from a template.
+func isAProgDir(dir string) bool {
+	content, err := os.ReadFile( //nolint:gosec
+		filepath.Join(dir, progDirMainFile))
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(content, []byte(progDirMainMarker))
+}
+
+// skipTreeDir returns true if the directory should not be searched for
+// program directories. As with the go command, directories whose names
+// start with '.' or '_' are skipped as are testdata and vendor directories.
+func skipTreeDir(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "_") ||
+		name == "testdata" ||
+		name == "vendor"
+}
+
+// findProgDirs returns the program directories found under the root
+// directory. The root directory itself is included if it is a program
+// directory.
+func findProgDirs(root string) ([]string, error) {
+	dirs := []string{}
+
+	err := filepath.WalkDir(root,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() {
+				return nil
+			}
+
+			if path != root && skipTreeDir(d.Name()) {
+				return fs.SkipDir
+			}
+
+			if isAProgDir(path) {
+				dirs = append(dirs, path)
+			}
+
+			return nil
+		})
+
+	return dirs, err
+}
+
+// forTarget returns a copy of the Prog with the target directory set to
+// the given directory and with all the values that are specific to a
+// target directory reset.
+func (prog *Prog) forTarget(dir string) (*Prog, error) {
+	mc, err := macros.NewCache()
+	if err != nil {
+		return nil, fmt.Errorf("cannot build the macro cache: %w", err)
+	}
+
+	tp := *prog
+	tp.exitStatus = 0
+	tp.recursive = false
+	tp.dir = dir
+	tp.name = filepath.Base(filepath.Clean(dir))
+	tp.fileChecks = map[string][]fileCheck{}
+	tp.targets = nil
+	tp.requirements = nil
+	tp.hooks = map[hookEvent][]hookCmd{}
+	tp.macroCache = mc
+	tp.journal = &journal{}
+	tp.summary = checkSummary{}
+
+	if err := checkProgName(tp.name); err != nil {
+		return nil, fmt.Errorf("bad program name: %w", err)
+	}
+
+	tp.addAllMacros()
+
+	return &tp, nil
+}
+
+// CheckTree finds all the program directories under the target directory
+// and checks each of them. It then reports a summary of the results.
+func (prog *Prog) CheckTree() {
+	defer prog.stack.Start("CheckTree", "Start")()
+
+	intro := prog.stack.Tag()
+
+	dirs, err := findProgDirs(prog.dir)
+	if err != nil {
+		fmt.Fprintf(prog.out, "Cannot search %q for program directories: %s\n",
+			prog.dir, err)
+		prog.SetExitStatus(esIOError)
+
+		return
+	}
+
+	if len(dirs) == 0 {
+		fmt.Fprintf(prog.out,
+			"No program directories were found under %q\n", prog.dir)
+		prog.SetExitStatus(esFailure)
+
+		return
+	}
+
+	rpt := runTasks(prog, dirs,
+		func(dir string) string { return dir },
+		func(tp *Prog, dir string) {
+			tp.verbosef("%s %30s: %q\n", intro, "program directory", dir)
+
+			dp, err := tp.forTarget(dir)
+			if err != nil {
+				fmt.Fprintf(tp.out, "Cannot check %q: %s\n", dir, err)
+				tp.SetExitStatus(esFailure)
+
+				return
+			}
+
+			// the program directories are already being checked in
+			// parallel so the checks within each directory are not
+			dp.jobs = 1
+			dp.CheckTargetDir()
+			tp.SetExitStatus(dp.exitStatus)
+		})
+
+	prog.reportTreeSummary(rpt.Entries())
+}
+
+// reportTreeSummary reports the status of each program directory and the
+// total numbers that passed and failed
+func (prog *Prog) reportTreeSummary(entries []*reportEntry) {
+	fmt.Fprintf(prog.out, "Program directories under %q:\n", prog.dir)
+
+	failCount := 0
+
+	for _, re := range entries {
+		status := "OK"
+		if re.exitStatus != 0 {
+			status = "FAILED"
+			failCount++
+		}
+
+		fmt.Fprintf(prog.out, "\t%-6s %s\n", status, re.path)
+	}
+
+	fmt.Fprintf(prog.out, "%d program %s checked: %d passed, %d failed\n",
+		len(entries), english.Plural("directory", len(entries)),
+		len(entries)-failCount, failCount)
+}
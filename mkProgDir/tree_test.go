@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestFindProgDirs(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"cmd/a/main.go":        "ps := makeParamSet(prog)",
+		"cmd/b/main.go":        "package main",
+		"cmd/c/d/main.go":      "ps := makeParamSet(prog)",
+		"cmd/.hidden/main.go":  "ps := makeParamSet(prog)",
+		"cmd/_skip/main.go":    "ps := makeParamSet(prog)",
+		"cmd/testdata/main.go": "ps := makeParamSet(prog)",
+		"vendor/x/main.go":     "ps := makeParamSet(prog)",
+	}
+
+	for name, content := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+
+		err := os.MkdirAll(filepath.Dir(name), 0o755)
+		if err != nil {
+			t.Fatalf("cannot make the directory for %q: %s", name, err)
+		}
+
+		err = os.WriteFile(name, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("cannot write %q: %s", name, err)
+		}
+	}
+
+	dirs, err := findProgDirs(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expDirs := []string{
+		filepath.Join(root, "cmd", "a"),
+		filepath.Join(root, "cmd", "c", "d"),
+	}
+
+	testhelper.DiffStringSlice(t, "findProgDirs", "dirs", dirs, expDirs)
+}
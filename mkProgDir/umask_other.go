@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "io/fs"
+
+// getUmask returns the umask of the current process. There is no umask on
+// this platform so it always returns zero.
+func getUmask() fs.FileMode {
+	return 0
+}
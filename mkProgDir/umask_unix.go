@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// getUmask returns the umask of the current process. Note that the umask
+// can only be found by setting it so it is set to zero and then restored
+// to its original value.
+func getUmask() fs.FileMode {
+	umask := syscall.Umask(0)
+	syscall.Umask(umask)
+
+	return fs.FileMode(umask) & fs.ModePerm //nolint:gosec
+}
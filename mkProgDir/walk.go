@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// walkProblem records a problem found while walking the template directory
+// and the exit status it should cause
+type walkProblem struct {
+	err error
+	es  int
+}
+
+// walkProblems collects the problems found while walking the template
+// directory so that they can all be reported together once the walk is
+// complete. If failFast is set the walk is stopped at the first problem.
+type walkProblems struct {
+	failFast bool
+	problems []walkProblem
+}
+
+// newWalkProblems returns a new walkProblems with the failFast flag taken
+// from the program settings
+func (prog *Prog) newWalkProblems() *walkProblems {
+	return &walkProblems{failFast: prog.failFast}
+}
+
+// add records the problem. It returns the error if the walk should be
+// stopped, otherwise it returns nil so that the walk will continue.
+func (wp *walkProblems) add(err error, es int) error {
+	wp.problems = append(wp.problems, walkProblem{err: err, es: es})
+
+	if wp.failFast {
+		return err
+	}
+
+	return nil
+}
+
+// recorded returns true if the error is the last problem recorded. This is
+// the error the walk will have returned if it was stopped by a problem.
+func (wp *walkProblems) recorded(err error) bool {
+	return len(wp.problems) > 0 && wp.problems[len(wp.problems)-1].err == err
+}
+
+// walkTemplate walks the template directory calling the walk function for
+// each entry. Any problems recorded by the walk function, together with any
+// error returned by the walk itself, are reported once the walk is
+// complete.
+func (prog *Prog) walkTemplate(wp *walkProblems, walkFunc fs.WalkDirFunc) {
+	err := fs.WalkDir(prog.templateFS, prog.walkerBase, walkFunc)
+	if err != nil && !wp.recorded(err) {
+		wp.problems = append(wp.problems, walkProblem{err: err, es: esTemplate})
+	}
+
+	prog.reportWalkProblems(wp)
+}
+
+// reportWalkProblems reports all the problems found walking the template
+// directory and sets the exit status accordingly
+func (prog *Prog) reportWalkProblems(wp *walkProblems) {
+	switch len(wp.problems) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(prog.out,
+			"Problem found walking the template directory: %s\n",
+			wp.problems[0].err)
+	default:
+		fmt.Fprintf(prog.out,
+			"%d problems found walking the template directory:\n",
+			len(wp.problems))
+
+		for _, p := range wp.problems {
+			fmt.Fprintf(prog.out, "\t%s\n", p.err)
+		}
+	}
+
+	if wp.failFast {
+		fmt.Fprintln(prog.out,
+			"\tthe walk was stopped at the first problem,"+
+				" there may be others")
+	}
+
+	for _, p := range wp.problems {
+		prog.SetExitStatus(p.es)
+	}
+}
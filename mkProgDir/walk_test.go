@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSetFileChecksProblems(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		failFast bool
+		expOut   string
+	}{
+		{
+			ID: testhelper.MkID("all problems"),
+			expOut: "2 problems found walking the template directory:\n" +
+				`	"a.txt.bad` + sfxCheck + `" :` +
+				" has no valid check-type suffix\n" +
+				`	"b.txt.ends` + sfxSeverity + `bad` + sfxCheck + `" :` +
+				` unknown severity: "bad"` + "\n",
+		},
+		{
+			ID:       testhelper.MkID("fail fast"),
+			failFast: true,
+			expOut: "Problem found walking the template directory: " +
+				`"a.txt.bad` + sfxCheck + `" :` +
+				" has no valid check-type suffix\n" +
+				"\tthe walk was stopped at the first problem," +
+				" there may be others\n",
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt":                &fstest.MapFile{Data: []byte("a")},
+		"a.txt.bad" + sfxCheck: &fstest.MapFile{Data: []byte("a")},
+		"b.txt.ends" + sfxSeverity + "bad" + sfxCheck: &fstest.MapFile{
+			Data: []byte("b"),
+		},
+		"c.txt.begins" + sfxCheck: &fstest.MapFile{Data: []byte("c")},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.failFast = tc.failFast
+		prog.addAllMacros()
+
+		prog.setFileChecks()
+
+		testhelper.DiffString(t, tc.IDStr(), "output", out.String(), tc.expOut)
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esTemplate)
+
+		if !tc.failFast {
+			testhelper.DiffInt(t, tc.IDStr(), "checked files",
+				len(prog.fileChecks), 1)
+		}
+	}
+}
+
+func TestCreateAllFilesProblems(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		failFast bool
+		expFiles map[string]string
+	}{
+		{
+			ID:       testhelper.MkID("all problems"),
+			expFiles: map[string]string{"b.txt": "b"},
+		},
+		{
+			ID:       testhelper.MkID("fail fast"),
+			failFast: true,
+			expFiles: map[string]string{},
+		},
+	}
+
+	tmplFS := fstest.MapFS{
+		"a.txt.bad" + sfxCheck: &fstest.MapFile{Data: []byte("a")},
+		"b.txt":                &fstest.MapFile{Data: []byte("b")},
+	}
+
+	for _, tc := range testCases {
+		t.Chdir(t.TempDir())
+
+		var out bytes.Buffer
+
+		prog := NewProg()
+		prog.out = &out
+		prog.dir = "prog"
+		prog.templateFS = tmplFS
+		prog.walkerBase = "."
+		prog.failFast = tc.failFast
+		prog.addAllMacros()
+
+		prog.CreateAllFiles()
+
+		testhelper.DiffInt(t, tc.IDStr(), "exit status",
+			prog.exitStatus, esTemplate)
+
+		err := testhelper.DiffVals(progFiles(t, "prog"), tc.expFiles)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\tunexpected files: %s", err)
+		}
+	}
+}